@@ -14,6 +14,9 @@ import (
 	"github.com/go-pg/pg/v10"
 	"github.com/jessevdk/go-flags"
 	"github.com/pkg/errors"
+
+	"github.com/mneverov/webapp101/pkg/cluster"
+	"github.com/mneverov/webapp101/pkg/config"
 )
 
 func startServer(port int, router http.Handler) *http.Server {
@@ -36,7 +39,13 @@ func startServer(port int, router http.Handler) *http.Server {
 	return server
 }
 
-func stopServerOnSignal(server *http.Server) {
+// shutdownOnSignal waits for a termination signal and shuts the service down
+// in order within the timeout: the server stops accepting API calls, the
+// scrapers are stopped and the pending metrics are stored. The DB connection
+// is closed by the caller afterwards.
+func shutdownOnSignal(
+	server *http.Server, cfgService *config.Service, timeout time.Duration,
+) {
 	sigCh := make(chan os.Signal, 1)
 	signal.Notify(sigCh,
 		syscall.SIGHUP,
@@ -48,13 +57,16 @@ func stopServerOnSignal(server *http.Server) {
 
 	log.Printf("shutdown webapp101 service due to received signal %q\n", sig)
 
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-	err := server.Shutdown(ctx)
-	cancel()
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
 
-	if err != nil {
+	if err := server.Shutdown(ctx); err != nil {
 		log.Printf("failed to shut down webapp101 service: %s\n", err)
 	}
+	if err := cfgService.Shutdown(ctx); err != nil {
+		log.Printf("failed to store pending metrics: %s\n", err)
+	}
+	log.Printf("webapp101 service is shut down\n")
 }
 
 func newDBConnection(opts DatabaseOpts) (*pg.DB, error) {
@@ -82,6 +94,35 @@ func newDBConnection(opts DatabaseOpts) (*pg.DB, error) {
 	return conn, nil
 }
 
+// newMembership creates the membership of the instance in the cluster. Leases
+// are renewed three times per TTL, so that a single failed renewal does not
+// move the configs to other instances.
+func newMembership(
+	conn *pg.DB, appOpts ApplicationOpts,
+) (*cluster.Membership, error) {
+	if appOpts.LeaseTTLSec < 3 {
+		return nil, errors.Errorf(
+			"lease TTL %ds must be at least 3s", appOpts.LeaseTTLSec,
+		)
+	}
+
+	id := appOpts.InstanceID
+	if id == "" {
+		host, err := os.Hostname()
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to get host name")
+		}
+		id = fmt.Sprintf("%s-%d", host, os.Getpid())
+	}
+	log.Printf("joining the cluster as instance %s\n", id)
+
+	return cluster.NewMembership(
+		cluster.NewPostgresStorage(conn),
+		id,
+		time.Duration(appOpts.LeaseTTLSec)*time.Second,
+	), nil
+}
+
 func migrate(conn *pg.DB, migrationOpts MigrationOpts) error {
 	if !migrationOpts.MigrateUp {
 		return nil
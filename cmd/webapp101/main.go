@@ -1,13 +1,14 @@
 package main
 
 import (
+	"context"
 	"fmt"
-	"net/http"
 	"os"
 	"time"
 
 	"github.com/go-chi/chi"
 
+	"github.com/mneverov/webapp101/pkg/certificate"
 	"github.com/mneverov/webapp101/pkg/config"
 	"github.com/mneverov/webapp101/pkg/metric"
 	"github.com/mneverov/webapp101/pkg/scrape"
@@ -37,8 +38,17 @@ type MigrationOpts struct {
 
 // ApplicationOpts contains options for the webapp101 application.
 type ApplicationOpts struct {
-	Port             int `long:"port" env:"PORT" default:"8080" description:"What port the app should start on"`
-	ClientTimeoutSec int `long:"client-timeout-sec" env:"CLIENT_TIMEOUT_SEC" default:"5" description:"Specifies a time limit for requests made by a scraper"`
+	Port                int    `long:"port" env:"PORT" default:"8080" description:"What port the app should start on"`
+	ClientTimeoutSec    int    `long:"client-timeout-sec" env:"CLIENT_TIMEOUT_SEC" default:"5" description:"Specifies a time limit for requests made by a scraper"`
+	ChecksDir           string `long:"checks-dir" env:"CHECKS_DIR" description:"A directory with Nagios-style check commands, exec probes are disabled if empty"`
+	ScrapeWorkers       int    `long:"scrape-workers" env:"SCRAPE_WORKERS" default:"64" description:"The maximum number of concurrent scrapes"`
+	ScrapesPerHostLimit int    `long:"scrapes-per-host-limit" env:"SCRAPES_PER_HOST_LIMIT" default:"0" description:"The maximum number of concurrent scrapes of a single host, unlimited if 0"`
+	ResultBuffer        int    `long:"result-buffer" env:"RESULT_BUFFER" default:"16" description:"The number of scrape results buffered per scraper when the DB is slow"`
+	ResultOverflow      string `long:"result-overflow" env:"RESULT_OVERFLOW" default:"block" choice:"block" choice:"drop-oldest" choice:"drop-newest" description:"What happens to a scrape result when the result buffer is full"`
+	ShutdownTimeoutSec  int    `long:"shutdown-timeout-sec" env:"SHUTDOWN_TIMEOUT_SEC" default:"30" description:"Specifies a time limit for stopping the scrapers and storing pending metrics on shutdown"`
+	Cluster             bool   `long:"cluster" env:"CLUSTER" description:"Indicates if the configs are shared with other instances using the same DB, so that every config is scraped by a single instance"`
+	InstanceID          string `long:"instance-id" env:"INSTANCE_ID" description:"A unique id of the instance in the cluster, the host name and the process id if empty"`
+	LeaseTTLSec         int    `long:"lease-ttl-sec" env:"LEASE_TTL_SEC" default:"15" description:"Specifies how long a config stays leased to an instance in the cluster without renewal"`
 }
 
 func main() {
@@ -60,24 +70,79 @@ func main() {
 		fmt.Printf("failed to migrate DB: %s. Terminating the app\n", err)
 		os.Exit(1)
 	}
+	certDB := certificate.NewPostgresStorage(conn)
+	certService := certificate.NewService(certDB)
+	certHandler := certificate.NewHandler(certService)
+
 	metricDB := metric.NewPostgresStorage(conn)
-	metricService := metric.NewService(metricDB)
+	metricService := metric.NewService(metricDB, certService)
 	metricHandler := metric.NewHandler(metricService)
 
-	client := &http.Client{Timeout: time.Duration(opts.AppOpts.ClientTimeoutSec) * time.Second}
-	scraperManager := scrape.NewInMemoryManager(client)
+	client := scrape.NewHTTPClient(
+		time.Duration(opts.AppOpts.ClientTimeoutSec) * time.Second,
+	)
+	registry := scrape.NewDefaultRegistry(client)
+	if opts.AppOpts.ChecksDir != "" {
+		err = registry.Register(
+			scrape.KindExec, scrape.NewExecFactory(opts.AppOpts.ChecksDir),
+		)
+		if err != nil {
+			fmt.Printf("failed to enable exec probes: %s. Terminating the app\n", err)
+			os.Exit(1)
+		}
+	}
+	scheduler, err := scrape.NewScheduler(
+		context.Background(),
+		opts.AppOpts.ScrapeWorkers,
+		opts.AppOpts.ScrapesPerHostLimit,
+		scrape.Pipeline{
+			Capacity: opts.AppOpts.ResultBuffer,
+			Overflow: scrape.OverflowPolicy(opts.AppOpts.ResultOverflow),
+		},
+	)
+	if err != nil {
+		fmt.Printf("failed to create scheduler: %s. Terminating the app\n", err)
+		os.Exit(1)
+	}
+	scraperManager := scrape.NewInMemoryManager(registry, scheduler)
 
 	cfgDB := config.NewPostgresStorage(conn)
 	cfgService := config.NewService(cfgDB, metricService, scraperManager)
+	if opts.AppOpts.Cluster {
+		membership, err := newMembership(conn, opts.AppOpts)
+		if err != nil {
+			fmt.Printf("failed to join the cluster: %s. Terminating the app\n", err)
+			os.Exit(1)
+		}
+		cfgService = config.NewClusterService(
+			cfgDB, metricService, scraperManager, membership,
+			time.Duration(opts.AppOpts.LeaseTTLSec)*time.Second,
+		)
+	}
 	cfgHandler := config.NewHandler(cfgService)
 
-	router := routes(metricHandler, cfgHandler)
+	err = cfgService.StartAll()
+	if err != nil {
+		fmt.Printf("failed to start scrapers: %s. Terminating the app\n", err)
+		os.Exit(1)
+	}
+
+	scrapeHandler := scrape.NewHandler(scheduler)
+
+	router := routes(metricHandler, cfgHandler, certHandler, scrapeHandler)
 	server := startServer(opts.AppOpts.Port, router)
-	stopServerOnSignal(server)
+	shutdownOnSignal(
+		server,
+		cfgService,
+		time.Duration(opts.AppOpts.ShutdownTimeoutSec)*time.Second,
+	)
 }
 
 func routes(
-	metricHandler *metric.Handler, configHandler *config.Handler,
+	metricHandler *metric.Handler,
+	configHandler *config.Handler,
+	certHandler *certificate.Handler,
+	scrapeHandler *scrape.Handler,
 ) chi.Router {
 	router := chi.NewRouter()
 	router.Route("/metrics", func(r chi.Router) {
@@ -87,5 +152,11 @@ func routes(
 		r.Get("/", configHandler.GetAll)
 		r.Post("/", configHandler.Create)
 	})
+	router.Route("/certificates", func(r chi.Router) {
+		r.Get("/", certHandler.GetAll)
+	})
+	router.Route("/scrapers", func(r chi.Router) {
+		r.Get("/stats", scrapeHandler.GetStats)
+	})
 	return router
 }
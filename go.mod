@@ -3,14 +3,18 @@ module github.com/mneverov/webapp101
 go 1.15
 
 require (
+	github.com/dop251/goja v0.0.0-20210406175830-1b11a6af686d
 	github.com/fortytw2/dockertest v0.0.0-20181228171220-480d52efdffe
 	github.com/go-chi/chi v1.5.1
 	github.com/go-pg/migrations/v8 v8.0.1
 	github.com/go-pg/pg/v10 v10.7.3
 	github.com/go-testfixtures/testfixtures/v3 v3.4.1
+	github.com/gorilla/websocket v1.4.2
 	github.com/jarcoal/httpmock v1.0.6
 	github.com/jessevdk/go-flags v1.4.0
 	github.com/lib/pq v1.9.0
+	github.com/miekg/dns v1.1.35
 	github.com/pkg/errors v0.9.1
 	github.com/stretchr/testify v1.6.1
+	google.golang.org/grpc v1.34.0
 )
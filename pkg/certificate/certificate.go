@@ -0,0 +1,80 @@
+package certificate
+
+//go:generate mockery --inpackage --all --case=underscore
+
+import (
+	"math"
+	"time"
+
+	"github.com/mneverov/webapp101/pkg/scrape"
+)
+
+// Certificate represents the last seen leaf TLS certificate of a config.
+type Certificate struct {
+	Name         string    `json:"name"           pg:"name,pk"`
+	Subject      string    `json:"subject"        pg:"subject,use_zero"`
+	Issuer       string    `json:"issuer"         pg:"issuer,use_zero"`
+	SANs         []string  `json:"sans"           pg:"sans,array"`
+	NotAfter     time.Time `json:"not_after"      pg:"not_after"`
+	DaysToExpiry int       `json:"days_to_expiry" pg:"-"`
+	UpdatedAt    time.Time `json:"updated_at"     pg:"updated_at"`
+}
+
+// Certificates contains a collection of certificates.
+type Certificates struct {
+	Data []Certificate `json:"data"`
+}
+
+type certificateStore interface {
+	Upsert(cert Certificate) (Certificate, error)
+	GetAll() ([]Certificate, error)
+}
+
+type certificateService interface {
+	GetAll() (Certificates, error)
+}
+
+// Service provides methods to work with Certificates.
+type Service struct {
+	store certificateStore
+}
+
+// NewService creates a new certificate service.
+func NewService(store certificateStore) *Service {
+	return &Service{store: store}
+}
+
+// Save stores the given scraped certificate as the current certificate of the
+// config with the given name.
+func (s *Service) Save(name string, cert scrape.Certificate) error {
+	_, err := s.store.Upsert(Certificate{
+		Name:      name,
+		Subject:   cert.Subject,
+		Issuer:    cert.Issuer,
+		SANs:      cert.SANs,
+		NotAfter:  cert.NotAfter,
+		UpdatedAt: time.Now(),
+	})
+	return err
+}
+
+// GetAll returns certificates of all existing configs ordered by the expiry
+// date, the soonest expiring first.
+func (s *Service) GetAll() (Certificates, error) {
+	certs, err := s.store.GetAll()
+	if err != nil {
+		return Certificates{}, err
+	}
+
+	now := time.Now()
+	for i := range certs {
+		certs[i].DaysToExpiry = daysToExpiry(certs[i].NotAfter, now)
+	}
+	return Certificates{Data: certs}, nil
+}
+
+// daysToExpiry returns the number of full days left until notAfter. It is
+// negative for an expired certificate.
+func daysToExpiry(notAfter, now time.Time) int {
+	return int(math.Floor(notAfter.Sub(now).Hours() / 24))
+}
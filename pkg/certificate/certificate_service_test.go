@@ -0,0 +1,98 @@
+package certificate
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mneverov/webapp101/pkg/scrape"
+)
+
+func TestCertificateService_Save(t *testing.T) {
+	t.Run("should propagate error from DB", func(t *testing.T) {
+		svc, db := createTestService()
+		db.On("Upsert", mock.Anything).
+			Return(Certificate{}, assert.AnError).
+			Once()
+
+		err := svc.Save(testCert.Name, scrape.Certificate{})
+
+		require.Error(t, err)
+		assert.Equal(t, assert.AnError, err)
+		db.AssertExpectations(t)
+	})
+
+	t.Run("should store scraped certificate", func(t *testing.T) {
+		svc, db := createTestService()
+		db.On("Upsert", mock.MatchedBy(func(c Certificate) bool {
+			return c.Name == testCert.Name &&
+				c.Subject == testCert.Subject &&
+				c.Issuer == testCert.Issuer &&
+				assert.ObjectsAreEqual(testCert.SANs, c.SANs) &&
+				c.NotAfter.Equal(testCert.NotAfter) &&
+				!c.UpdatedAt.IsZero()
+		})).
+			Return(testCert, nil).
+			Once()
+
+		err := svc.Save(testCert.Name, scrape.Certificate{
+			Subject:  testCert.Subject,
+			Issuer:   testCert.Issuer,
+			SANs:     testCert.SANs,
+			NotAfter: testCert.NotAfter,
+		})
+
+		assert.NoError(t, err)
+		db.AssertExpectations(t)
+	})
+}
+
+func TestCertificateService_GetAll(t *testing.T) {
+	t.Run("should propagate error from DB", func(t *testing.T) {
+		svc, db := createTestService()
+		db.On("GetAll").
+			Return(nil, assert.AnError).
+			Once()
+
+		_, err := svc.GetAll()
+
+		require.Error(t, err)
+		assert.Equal(t, assert.AnError, err)
+		db.AssertExpectations(t)
+	})
+
+	t.Run("should return certificates with days to expiry", func(t *testing.T) {
+		svc, db := createTestService()
+		expired := testCert
+		expired.Name = "expired"
+		expired.NotAfter = time.Now().Add(-time.Hour)
+		db.On("GetAll").
+			Return([]Certificate{expired, testCert}, nil).
+			Once()
+
+		res, err := svc.GetAll()
+
+		assert.NoError(t, err)
+		require.Len(t, res.Data, 2)
+		assert.Equal(t, -1, res.Data[0].DaysToExpiry)
+		assert.Equal(t, 1, res.Data[1].DaysToExpiry)
+		db.AssertExpectations(t)
+	})
+}
+
+func TestDaysToExpiry(t *testing.T) {
+	now := time.Now()
+
+	assert.Equal(t, 0, daysToExpiry(now.Add(time.Hour), now))
+	assert.Equal(t, 30, daysToExpiry(now.Add(30*24*time.Hour), now))
+	assert.Equal(t, -1, daysToExpiry(now.Add(-time.Hour), now))
+}
+
+func createTestService() (*Service, *mockCertificateStore) {
+	db := mockCertificateStore{}
+	svc := NewService(&db)
+	return svc, &db
+}
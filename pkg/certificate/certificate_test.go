@@ -0,0 +1,39 @@
+package certificate
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/go-pg/pg/v10"
+
+	"github.com/mneverov/webapp101/pkg/testutil"
+)
+
+var (
+	testCert = Certificate{
+		Name:     "example",
+		Subject:  "CN=www.example.org",
+		Issuer:   "CN=DigiCert TLS RSA SHA256 2020 CA1",
+		SANs:     []string{"www.example.org", "example.com"},
+		NotAfter: time.Now().Add(48 * time.Hour).Truncate(time.Millisecond),
+	}
+
+	dbOpts pg.Options
+)
+
+func TestMain(m *testing.M) {
+	opts := pg.Options{
+		Addr:     "127.0.0.1:5432",
+		User:     "webapp101",
+		Password: "webapp101",
+		Database: "webapp101_test",
+	}
+	os.Exit(func() int {
+		container := testutil.StartPostgresContainer(opts)
+		opts.Addr = container.Addr
+		dbOpts = opts
+		defer container.Shutdown()
+		return m.Run()
+	}())
+}
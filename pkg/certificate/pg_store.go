@@ -0,0 +1,50 @@
+package certificate
+
+import (
+	"github.com/go-pg/pg/v10"
+	"github.com/pkg/errors"
+)
+
+// Postgres provides interaction with Postgresql DB for certificates.
+type Postgres struct {
+	db *pg.DB
+}
+
+// NewPostgresStorage creates a new instance of the Postgres Storage.
+func NewPostgresStorage(db *pg.DB) *Postgres {
+	return &Postgres{db: db}
+}
+
+// Upsert creates a certificate or replaces the existing certificate of the
+// same config.
+func (s *Postgres) Upsert(cert Certificate) (Certificate, error) {
+	_, err := s.db.Model(&cert).
+		OnConflict("(name) DO UPDATE").
+		Set("subject = EXCLUDED.subject").
+		Set("issuer = EXCLUDED.issuer").
+		Set("sans = EXCLUDED.sans").
+		Set("not_after = EXCLUDED.not_after").
+		Set("updated_at = EXCLUDED.updated_at").
+		Returning("*").
+		Insert()
+	if err != nil {
+		return Certificate{},
+			errors.Wrapf(err, "failed to store certificate %s", cert.Name)
+	}
+
+	return cert, nil
+}
+
+// GetAll returns certificates of all existing configs ordered by the expiry
+// date, the soonest expiring first.
+func (s *Postgres) GetAll() ([]Certificate, error) {
+	certs := make([]Certificate, 0)
+	err := s.db.Model(&certs).
+		Where("certificate.name IN (SELECT name FROM configs WHERE deleted_at IS NULL)").
+		Order("not_after ASC").
+		Select()
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get all certificates")
+	}
+	return certs, nil
+}
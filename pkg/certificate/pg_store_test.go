@@ -0,0 +1,75 @@
+package certificate
+
+import (
+	"testing"
+	"time"
+
+	_ "github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mneverov/webapp101/pkg/testutil"
+)
+
+func TestCertificateDB_GetAll(t *testing.T) {
+	conn := testutil.TestDB(t, dbOpts, "certificate")
+	db := NewPostgresStorage(conn)
+
+	t.Run("should return certificates ordered by expiry", func(t *testing.T) {
+		res, err := db.GetAll()
+
+		require.NoError(t, err)
+		require.Len(t, res, 2)
+		assert.Equal(t, "github_jobs", res[0].Name)
+		assert.Equal(t, "example", res[1].Name)
+		assert.Equal(t,
+			[]string{"www.example.org", "example.com", "example.net"},
+			res[1].SANs,
+		)
+	})
+
+	t.Run("should skip certificates of deleted configs", func(t *testing.T) {
+		_, err := conn.Exec(
+			"UPDATE configs SET deleted_at = NOW() WHERE name = ?", "github_jobs",
+		)
+		require.NoError(t, err)
+
+		res, err := db.GetAll()
+
+		require.NoError(t, err)
+		require.Len(t, res, 1)
+		assert.Equal(t, "example", res[0].Name)
+	})
+}
+
+func TestCertificateDB_Upsert(t *testing.T) {
+	conn := testutil.TestDB(t, dbOpts, "certificate")
+	db := NewPostgresStorage(conn)
+
+	t.Run("should return error when config does not exist", func(t *testing.T) {
+		cert := testCert
+		cert.Name = "unknown_config"
+
+		_, err := db.Upsert(cert)
+
+		require.Error(t, err)
+		assert.Regexp(t, cert.Name, err)
+	})
+
+	t.Run("should replace existing certificate", func(t *testing.T) {
+		cert := testCert
+		cert.UpdatedAt = time.Now().Truncate(time.Millisecond)
+
+		res, err := db.Upsert(cert)
+		require.NoError(t, err)
+		assert.Equal(t, cert.Subject, res.Subject)
+
+		all, err := db.GetAll()
+		require.NoError(t, err)
+		require.Len(t, all, 2)
+		// the replaced certificate now expires first.
+		assert.Equal(t, cert.Name, all[0].Name)
+		assert.Equal(t, cert.SANs, all[0].SANs)
+		assert.True(t, cert.NotAfter.Equal(all[0].NotAfter))
+	})
+}
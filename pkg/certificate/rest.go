@@ -0,0 +1,43 @@
+package certificate
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+)
+
+// Handler represents a certificate handler.
+type Handler struct {
+	service certificateService
+}
+
+// NewHandler creates a new certificate handler.
+func NewHandler(service certificateService) *Handler {
+	return &Handler{service: service}
+}
+
+// GetAll returns a list of certificates of all configs ordered by the expiry
+// date, the soonest expiring first.
+// GET /certificates.
+func (h *Handler) GetAll(w http.ResponseWriter, _ *http.Request) {
+	certs, err := h.service.GetAll()
+	if err != nil {
+		log.Printf("%+v\n", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	output, err := json.Marshal(certs)
+	if err != nil {
+		log.Printf("failed to marshal response %+v\n", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("content-type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_, err = w.Write(output)
+	if err != nil {
+		log.Printf("failed to write response: %+v\n", err)
+	}
+}
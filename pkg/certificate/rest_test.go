@@ -0,0 +1,58 @@
+package certificate
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const certificatesPath = "/certificates"
+
+func TestCertificateHandler_GetAll(t *testing.T) {
+	t.Run("should propagate service error", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodGet, certificatesPath, nil)
+		w := httptest.NewRecorder()
+
+		router, certService := createTestRouter()
+		certService.On("GetAll").
+			Return(Certificates{}, assert.AnError).
+			Once()
+
+		router.ServeHTTP(w, r)
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+		certService.AssertExpectations(t)
+	})
+
+	t.Run("should return found certificates", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodGet, certificatesPath, nil)
+		w := httptest.NewRecorder()
+
+		router, certService := createTestRouter()
+		expectedCerts := Certificates{Data: []Certificate{testCert}}
+		certService.On("GetAll").
+			Return(expectedCerts, nil).
+			Once()
+
+		router.ServeHTTP(w, r)
+
+		expectedJSON, err := json.Marshal(expectedCerts)
+		require.NoError(t, err)
+		assert.JSONEq(t, string(expectedJSON), w.Body.String())
+		assert.Equal(t, http.StatusOK, w.Code)
+		certService.AssertExpectations(t)
+	})
+}
+
+func createTestRouter() (http.Handler, *mockCertificateService) {
+	router := chi.NewRouter()
+	svc := mockCertificateService{}
+	handler := NewHandler(&svc)
+	router.Get(certificatesPath, handler.GetAll)
+
+	return router, &svc
+}
@@ -0,0 +1,122 @@
+package cluster
+
+//go:generate mockery --inpackage --all --case=underscore
+
+import (
+	"hash/fnv"
+	"time"
+)
+
+// Instance represents a webapp101 instance registered in the cluster.
+type Instance struct {
+	ID          string    `json:"id"           pg:"id,pk"`
+	HeartbeatAt time.Time `json:"heartbeat_at" pg:"heartbeat_at"`
+}
+
+// Lease represents the right of an instance to scrape a config until the
+// lease expires.
+type Lease struct {
+	ConfigName string    `json:"config_name" pg:"config_name,pk"`
+	InstanceID string    `json:"instance_id" pg:"instance_id"`
+	ExpiresAt  time.Time `json:"expires_at"  pg:"expires_at"`
+}
+
+type membershipStore interface {
+	Heartbeat(id string, ttl time.Duration) ([]Instance, error)
+	Acquire(id string, names []string, ttl time.Duration) ([]string, error)
+	ReleaseExcept(id string, names []string) error
+	Leave(id string) error
+}
+
+// Membership represents the membership of an instance in a cluster of
+// instances sharing the DB. Configs are assigned to the live instances with
+// rendezvous hashing, an instance scrapes only the configs it holds a lease
+// on, so that a config is scraped by a single instance even while the cluster
+// is rebalanced. Heartbeats and leases expire unless renewed within the TTL,
+// then the configs of a dead instance are taken over by the others.
+type Membership struct {
+	store membershipStore
+	id    string
+	ttl   time.Duration
+}
+
+// NewMembership creates a new membership of the instance with the given id.
+func NewMembership(
+	store membershipStore, id string, ttl time.Duration,
+) *Membership {
+	return &Membership{store: store, id: id, ttl: ttl}
+}
+
+// ID returns the id of the instance.
+func (m *Membership) ID() string {
+	return m.id
+}
+
+// Assign renews the heartbeat of the instance and returns the names of the
+// given configs assigned to it. The configs assigned to the instance may
+// still be leased to their previous owners.
+func (m *Membership) Assign(names []string) ([]string, error) {
+	instances, err := m.store.Heartbeat(m.id, m.ttl)
+	if err != nil {
+		return nil, err
+	}
+
+	assigned := make([]string, 0, len(names))
+	for _, name := range names {
+		if owner(instances, name) == m.id {
+			assigned = append(assigned, name)
+		}
+	}
+	return assigned, nil
+}
+
+// Release releases the leases of the instance on all configs but the given
+// ones. Scrapers of the released configs must be stopped beforehand, since
+// other instances may start scraping them at once.
+func (m *Membership) Release(names []string) error {
+	return m.store.ReleaseExcept(m.id, names)
+}
+
+// Acquire leases the given configs to the instance or renews its leases and
+// returns the names of the leased configs. A config is leased only after the
+// lease of its previous owner is released or expired.
+func (m *Membership) Acquire(names []string) ([]string, error) {
+	return m.store.Acquire(m.id, names, m.ttl)
+}
+
+// Leave releases all leases of the instance and removes it from the cluster,
+// so that the other instances take its configs over without waiting for the
+// leases to expire.
+func (m *Membership) Leave() error {
+	return m.store.Leave(m.id)
+}
+
+// owner returns the id of the instance the config with the given name is
+// assigned to. The assignment is stable: when an instance joins or leaves
+// the cluster only the configs assigned to it are moved.
+func owner(instances []Instance, name string) string {
+	var id string
+	var max uint64
+	for _, instance := range instances {
+		h := fnv.New64a()
+		_, _ = h.Write([]byte(instance.ID))
+		_, _ = h.Write([]byte{0})
+		_, _ = h.Write([]byte(name))
+		if weight := mix(h.Sum64()); id == "" || weight > max {
+			id, max = instance.ID, weight
+		}
+	}
+	return id
+}
+
+// mix finalizes the hash, so that every bit of the weight depends on every
+// bit of the input and instance ids that differ in a single byte are not
+// ordered the same for all configs.
+func mix(h uint64) uint64 {
+	h ^= h >> 33
+	h *= 0xff51afd7ed558ccd
+	h ^= h >> 33
+	h *= 0xc4ceb9fe1a85ec53
+	h ^= h >> 33
+	return h
+}
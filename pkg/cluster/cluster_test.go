@@ -0,0 +1,115 @@
+package cluster
+
+import (
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/go-pg/pg/v10"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mneverov/webapp101/pkg/testutil"
+)
+
+const testTTL = time.Minute
+
+var (
+	dbOpts    pg.Options
+	instanceA = Instance{ID: "a"}
+	instanceB = Instance{ID: "b"}
+)
+
+func TestMain(m *testing.M) {
+	opts := pg.Options{
+		Addr:     "127.0.0.1:5432",
+		User:     "webapp101",
+		Password: "webapp101",
+		Database: "webapp101_test",
+	}
+	os.Exit(func() int {
+		container := testutil.StartPostgresContainer(opts)
+		opts.Addr = container.Addr
+		dbOpts = opts
+		defer container.Shutdown()
+		return m.Run()
+	}())
+}
+
+func TestOwner(t *testing.T) {
+	names := make([]string, 100)
+	for i := range names {
+		names[i] = fmt.Sprintf("config_%d", i)
+	}
+
+	t.Run("should return empty owner without instances", func(t *testing.T) {
+		assert.Empty(t, owner(nil, "config"))
+	})
+
+	t.Run("should not depend on order of instances", func(t *testing.T) {
+		for _, name := range names {
+			assert.Equal(t,
+				owner([]Instance{instanceA, instanceB}, name),
+				owner([]Instance{instanceB, instanceA}, name),
+			)
+		}
+	})
+
+	t.Run("should spread configs across instances", func(t *testing.T) {
+		owned := make(map[string]int)
+		for _, name := range names {
+			owned[owner([]Instance{instanceA, instanceB}, name)]++
+		}
+		assert.Greater(t, owned[instanceA.ID], 20)
+		assert.Greater(t, owned[instanceB.ID], 20)
+	})
+
+	t.Run("should move only configs of removed instance", func(t *testing.T) {
+		instanceC := Instance{ID: "c"}
+		for _, name := range names {
+			before := owner([]Instance{instanceA, instanceB, instanceC}, name)
+			after := owner([]Instance{instanceA, instanceB}, name)
+			if before != instanceC.ID {
+				assert.Equal(t, before, after)
+			}
+		}
+	})
+}
+
+func TestMembership_Assign(t *testing.T) {
+	names := []string{"config_0", "config_1", "config_2", "config_3"}
+
+	t.Run("should propagate error from heartbeat", func(t *testing.T) {
+		store := &mockMembershipStore{}
+		store.On("Heartbeat", instanceA.ID, testTTL).
+			Return(nil, assert.AnError).
+			Once()
+
+		_, err := NewMembership(store, instanceA.ID, testTTL).Assign(names)
+		assert.Equal(t, assert.AnError, err)
+		store.AssertExpectations(t)
+	})
+
+	t.Run("should return configs assigned to instance", func(t *testing.T) {
+		var assigned []string
+		for _, name := range names {
+			if owner([]Instance{instanceA, instanceB}, name) == instanceA.ID {
+				assigned = append(assigned, name)
+			}
+		}
+		require.NotEmpty(t, assigned)
+		require.Less(t, len(assigned), len(names))
+		store := &mockMembershipStore{}
+		store.On("Heartbeat", instanceA.ID, testTTL).
+			Return([]Instance{instanceA, instanceB}, nil).
+			Once()
+
+		res, err := NewMembership(store, instanceA.ID, testTTL).Assign(names)
+		assert.NoError(t, err)
+		assert.Equal(t, assigned, res)
+		store.AssertNotCalled(t, "Acquire", mock.Anything, mock.Anything, mock.Anything)
+		store.AssertNotCalled(t, "ReleaseExcept", mock.Anything, mock.Anything)
+	})
+}
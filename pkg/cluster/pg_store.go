@@ -0,0 +1,104 @@
+package cluster
+
+import (
+	"time"
+
+	"github.com/go-pg/pg/v10"
+	"github.com/pkg/errors"
+)
+
+// Postgres provides interaction with Postgresql DB for the cluster
+// membership. All timestamps are taken from the DB clock, so that instances
+// with skewed clocks agree on the expiry of heartbeats and leases.
+type Postgres struct {
+	db *pg.DB
+}
+
+// NewPostgresStorage creates a new instance of the Postgres Storage.
+func NewPostgresStorage(db *pg.DB) *Postgres {
+	return &Postgres{db: db}
+}
+
+// Heartbeat registers the instance with the given id or renews its heartbeat,
+// removes instances whose heartbeat is older than ttl and returns the live
+// instances ordered by id.
+func (s *Postgres) Heartbeat(id string, ttl time.Duration) ([]Instance, error) {
+	_, err := s.db.Model(&Instance{ID: id}).
+		Value("heartbeat_at", "NOW()").
+		OnConflict("(id) DO UPDATE").
+		Set("heartbeat_at = EXCLUDED.heartbeat_at").
+		Insert()
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to renew heartbeat of instance %s", id)
+	}
+
+	_, err = s.db.Model((*Instance)(nil)).
+		Where("heartbeat_at < NOW() - ? * INTERVAL '1 millisecond'", ttl.Milliseconds()).
+		Delete()
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to remove dead instances")
+	}
+
+	instances := make([]Instance, 0)
+	err = s.db.Model(&instances).Order("id ASC").Select()
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get live instances")
+	}
+	return instances, nil
+}
+
+// Acquire leases the configs with the given names to the instance for ttl
+// and returns the names of the leased configs. Leases held by the instance
+// are renewed, leases held by other instances are taken over only when they
+// are expired.
+func (s *Postgres) Acquire(
+	id string, names []string, ttl time.Duration,
+) ([]string, error) {
+	leased := make([]string, 0, len(names))
+	if len(names) == 0 {
+		return leased, nil
+	}
+
+	_, err := s.db.Query(&leased, `
+		INSERT INTO leases (config_name, instance_id, expires_at)
+		SELECT name, ?0, NOW() + ?1 * INTERVAL '1 millisecond'
+		FROM UNNEST(?2::TEXT[]) AS name
+		ON CONFLICT (config_name) DO UPDATE
+		SET instance_id = EXCLUDED.instance_id, expires_at = EXCLUDED.expires_at
+		WHERE leases.instance_id = EXCLUDED.instance_id
+		   OR leases.expires_at < NOW()
+		RETURNING config_name`,
+		id, ttl.Milliseconds(), pg.Array(names),
+	)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to acquire leases of instance %s", id)
+	}
+	return leased, nil
+}
+
+// ReleaseExcept releases the leases of the instance on all configs but the
+// ones with the given names.
+func (s *Postgres) ReleaseExcept(id string, names []string) error {
+	_, err := s.db.Model((*Lease)(nil)).
+		Where("instance_id = ?", id).
+		Where("NOT (config_name = ANY(?))", pg.Array(names)).
+		Delete()
+	if err != nil {
+		return errors.Wrapf(err, "failed to release leases of instance %s", id)
+	}
+	return nil
+}
+
+// Leave releases all leases of the instance and removes it from the cluster.
+func (s *Postgres) Leave(id string) error {
+	_, err := s.db.Model((*Lease)(nil)).Where("instance_id = ?", id).Delete()
+	if err != nil {
+		return errors.Wrapf(err, "failed to release leases of instance %s", id)
+	}
+
+	_, err = s.db.Model(&Instance{ID: id}).WherePK().Delete()
+	if err != nil {
+		return errors.Wrapf(err, "failed to remove instance %s", id)
+	}
+	return nil
+}
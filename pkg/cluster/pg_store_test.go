@@ -0,0 +1,181 @@
+package cluster
+
+import (
+	"testing"
+	"time"
+
+	_ "github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mneverov/webapp101/pkg/testutil"
+)
+
+func TestClusterDB_Heartbeat(t *testing.T) {
+	conn := testutil.TestDB(t, dbOpts, "cluster")
+	db := NewPostgresStorage(conn)
+
+	t.Run("should return registered instances", func(t *testing.T) {
+		_, err := db.Heartbeat(instanceB.ID, testTTL)
+		require.NoError(t, err)
+
+		res, err := db.Heartbeat(instanceA.ID, testTTL)
+		assert.NoError(t, err)
+		require.Len(t, res, 2)
+		assert.Equal(t, instanceA.ID, res[0].ID)
+		assert.Equal(t, instanceB.ID, res[1].ID)
+	})
+
+	t.Run("should remove instances with expired heartbeat", func(t *testing.T) {
+		_, err := conn.Model((*Instance)(nil)).
+			Set("heartbeat_at = NOW() - INTERVAL '1 hour'").
+			Where("id = ?", instanceB.ID).
+			Update()
+		require.NoError(t, err)
+
+		res, err := db.Heartbeat(instanceA.ID, testTTL)
+		assert.NoError(t, err)
+		require.Len(t, res, 1)
+		assert.Equal(t, instanceA.ID, res[0].ID)
+	})
+}
+
+func TestClusterDB_Acquire(t *testing.T) {
+	conn := testutil.TestDB(t, dbOpts, "cluster")
+	db := NewPostgresStorage(conn)
+
+	t.Run("should lease config to a single instance", func(t *testing.T) {
+		res, err := db.Acquire(instanceA.ID, []string{"example"}, testTTL)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"example"}, res)
+
+		res, err = db.Acquire(instanceB.ID, []string{"example", "github_jobs"}, testTTL)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"github_jobs"}, res)
+	})
+
+	t.Run("should renew own lease", func(t *testing.T) {
+		res, err := db.Acquire(instanceA.ID, []string{"example"}, testTTL)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"example"}, res)
+	})
+
+	t.Run("should take over expired lease", func(t *testing.T) {
+		_, err := db.Acquire(instanceA.ID, []string{"example"}, time.Millisecond)
+		require.NoError(t, err)
+		time.Sleep(10 * time.Millisecond)
+
+		res, err := db.Acquire(instanceB.ID, []string{"example"}, testTTL)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"example"}, res)
+	})
+
+	t.Run("should return empty slice without configs", func(t *testing.T) {
+		res, err := db.Acquire(instanceA.ID, nil, testTTL)
+		assert.NoError(t, err)
+		assert.NotNil(t, res)
+		assert.Empty(t, res)
+	})
+}
+
+func TestClusterDB_ReleaseExcept(t *testing.T) {
+	conn := testutil.TestDB(t, dbOpts, "cluster")
+	db := NewPostgresStorage(conn)
+
+	t.Run("should release leases of other configs", func(t *testing.T) {
+		_, err := db.Acquire(instanceA.ID, []string{"example", "github_jobs"}, testTTL)
+		require.NoError(t, err)
+
+		err = db.ReleaseExcept(instanceA.ID, []string{"example"})
+		assert.NoError(t, err)
+
+		res, err := db.Acquire(instanceB.ID, []string{"example", "github_jobs"}, testTTL)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"github_jobs"}, res)
+	})
+
+	t.Run("should release all leases without configs", func(t *testing.T) {
+		err := db.ReleaseExcept(instanceA.ID, nil)
+		assert.NoError(t, err)
+
+		res, err := db.Acquire(instanceB.ID, []string{"example"}, testTTL)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"example"}, res)
+	})
+}
+
+func TestClusterDB_Leave(t *testing.T) {
+	conn := testutil.TestDB(t, dbOpts, "cluster")
+	db := NewPostgresStorage(conn)
+
+	t.Run("should release leases and remove instance", func(t *testing.T) {
+		_, err := db.Heartbeat(instanceA.ID, testTTL)
+		require.NoError(t, err)
+		_, err = db.Acquire(instanceA.ID, []string{"example"}, testTTL)
+		require.NoError(t, err)
+
+		err = db.Leave(instanceA.ID)
+		assert.NoError(t, err)
+
+		instances, err := db.Heartbeat(instanceB.ID, testTTL)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{instanceB.ID}, ids(instances))
+		res, err := db.Acquire(instanceB.ID, []string{"example"}, testTTL)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"example"}, res)
+	})
+}
+
+func TestMembership_Cluster(t *testing.T) {
+	conn := testutil.TestDB(t, dbOpts, "cluster")
+	names := []string{"example", "github_jobs", "config_0", "config_1", "config_2"}
+	members := []*Membership{
+		NewMembership(NewPostgresStorage(conn), instanceA.ID, testTTL),
+		NewMembership(NewPostgresStorage(conn), instanceB.ID, testTTL),
+		NewMembership(NewPostgresStorage(conn), "c", testTTL),
+	}
+
+	// reconcile returns the instance each config is scraped by after all
+	// members reconciled twice, so that released leases are acquired.
+	reconcile := func(t *testing.T, members []*Membership) map[string]string {
+		scrapedBy := make(map[string]string)
+		for i := 0; i < 2; i++ {
+			scrapedBy = make(map[string]string)
+			for _, m := range members {
+				assigned, err := m.Assign(names)
+				require.NoError(t, err)
+				require.NoError(t, m.Release(assigned))
+				leased, err := m.Acquire(assigned)
+				require.NoError(t, err)
+				for _, name := range leased {
+					require.NotContains(t, scrapedBy, name, "%s is scraped twice", name)
+					scrapedBy[name] = m.ID()
+				}
+			}
+		}
+		return scrapedBy
+	}
+
+	t.Run("should scrape every config exactly once", func(t *testing.T) {
+		scrapedBy := reconcile(t, members)
+		assert.Len(t, scrapedBy, len(names))
+	})
+
+	t.Run("should rebalance configs when instance leaves", func(t *testing.T) {
+		require.NoError(t, members[2].Leave())
+
+		scrapedBy := reconcile(t, members[:2])
+		assert.Len(t, scrapedBy, len(names))
+		for _, id := range scrapedBy {
+			assert.NotEqual(t, "c", id)
+		}
+	})
+}
+
+func ids(instances []Instance) []string {
+	res := make([]string, len(instances))
+	for i, instance := range instances {
+		res[i] = instance.ID
+	}
+	return res
+}
@@ -3,7 +3,11 @@ package config
 //go:generate mockery --inpackage --all --case=underscore
 
 import (
+	"context"
+	"encoding/json"
 	"log"
+	"reflect"
+	"sync"
 	"time"
 
 	"github.com/pkg/errors"
@@ -11,12 +15,31 @@ import (
 	"github.com/mneverov/webapp101/pkg/scrape"
 )
 
-// Config represents a metric config.
+// Config represents a metric config. Kind defines the kind of probe, an empty
+// kind means http, and settings contain kind-specific settings. Timeout limits
+// every scrape, if set. Method, headers and body define the request sent on
+// every scrape, an empty method means GET. Script is a JavaScript check run
+// against every response. Retry defines how failed scrapes are retried,
+// failing defines the scraping interval while the target is failing. Auth
+// secrets and values of headers carrying credentials are never returned by the
+// service.
 type Config struct {
-	Name             string    `json:"name"              pg:"name,pk"`
-	URL              string    `json:"url"               pg:"url,use_zero"`
-	ScrapingInterval string    `json:"scraping_interval" pg:"scraping_interval,use_zero"`
-	DeletedAt        time.Time `json:"-"                 pg:"deleted_at,soft_delete"`
+	Name             string                 `json:"name"                      pg:"name,pk"`
+	Kind             scrape.Kind            `json:"kind,omitempty"            pg:"kind,use_zero"`
+	URL              string                 `json:"url"                       pg:"url,use_zero"`
+	ScrapingInterval string                 `json:"scraping_interval"         pg:"scraping_interval,use_zero"`
+	Timeout          string                 `json:"timeout,omitempty"         pg:"timeout,use_zero"`
+	Method           string                 `json:"method,omitempty"          pg:"method,use_zero"`
+	Headers          map[string]string      `json:"headers,omitempty"         pg:"headers"`
+	Body             string                 `json:"body,omitempty"            pg:"body,use_zero"`
+	Auth             *scrape.Auth           `json:"auth,omitempty"            pg:"auth"`
+	RedirectPolicy   *scrape.RedirectPolicy `json:"redirect_policy,omitempty" pg:"redirect_policy"`
+	Assertions       []scrape.Assertion     `json:"assertions,omitempty"      pg:"assertions"`
+	Script           string                 `json:"script,omitempty"          pg:"script,use_zero"`
+	Retry            *scrape.RetryPolicy    `json:"retry,omitempty"           pg:"retry"`
+	Failing          *scrape.FailingPolicy  `json:"failing,omitempty"         pg:"failing"`
+	Settings         json.RawMessage        `json:"settings,omitempty"        pg:"settings"`
+	DeletedAt        time.Time              `json:"-"                         pg:"deleted_at,soft_delete"`
 }
 
 // Configs contains a collection of configs.
@@ -45,9 +68,21 @@ type metricService interface {
 }
 
 type scraperManager interface {
-	Run(name, url string, scrapePeriod time.Duration) (<-chan scrape.Result, error)
-	Update(name, url string, scrapePeriod time.Duration) (<-chan scrape.Result, error)
+	Run(name string, target scrape.Target) (<-chan scrape.Result, error)
+	RunDelayed(
+		name string, target scrape.Target, delay time.Duration,
+	) (<-chan scrape.Result, error)
+	Update(name string, target scrape.Target) (<-chan scrape.Result, error)
 	Stop(name string) error
+	Validate(target scrape.Target) error
+	Shutdown(ctx context.Context) error
+}
+
+type membership interface {
+	Assign(names []string) ([]string, error)
+	Release(names []string) error
+	Acquire(names []string) ([]string, error)
+	Leave() error
 }
 
 // Service provides methods to work with Configs.
@@ -55,6 +90,32 @@ type Service struct {
 	store          configStore
 	metricService  metricService
 	scraperManager scraperManager
+	// consumers tracks the running consumers of scrape results.
+	consumers sync.WaitGroup
+
+	// membership is set when configs are shared by a cluster of instances,
+	// then scrapers are run only for the leased configs and reconciled
+	// every third of the lease TTL.
+	membership membership
+	ttl        time.Duration
+	// mu serializes the reconciliations.
+	mu sync.Mutex
+	// leaseMu guards the scrapers run in the cluster and the lease expiry.
+	// It is never held while waiting for the DB or the consumers, so that
+	// the scrapers are stopped in time even if a reconciliation is stuck.
+	leaseMu sync.Mutex
+	// running contains the configs scrapers are run for in the cluster.
+	running map[string]runningConfig
+	// stopping contains the consumers of the stopped scrapers. The leases of
+	// their configs are released only after the consumers are finished.
+	stopping map[string][]<-chan struct{}
+	// leasedUntil is the time the scrapers are stopped at by expiry unless
+	// the leases are renewed.
+	leasedUntil time.Time
+	expiry      *time.Timer
+	stopOnce    sync.Once
+	stopCh      chan struct{}
+	coordinator sync.WaitGroup
 }
 
 // NewService creates a new config service.
@@ -68,67 +129,144 @@ func NewService(
 	}
 }
 
-// GetAll returns all existing configs.
+// NewClusterService creates a new config service of an instance in a
+// cluster. Scrapers are run only for the configs leased through the
+// membership for the given TTL. Leases are renewed every third of the TTL,
+// scrapers are stopped when the leases are not renewed for two thirds of it.
+func NewClusterService(
+	store configStore,
+	metricService metricService,
+	scraperManager scraperManager,
+	membership membership,
+	ttl time.Duration,
+) *Service {
+	return &Service{
+		store:          store,
+		metricService:  metricService,
+		scraperManager: scraperManager,
+		membership:     membership,
+		ttl:            ttl,
+		running:        make(map[string]runningConfig),
+		stopping:       make(map[string][]<-chan struct{}),
+		stopCh:         make(chan struct{}),
+	}
+}
+
+// GetAll returns all existing configs without secrets.
 func (s *Service) GetAll() (Configs, error) {
 	configs, err := s.store.GetAll()
 	if err != nil {
 		return Configs{}, err
 	}
+
+	for i := range configs {
+		configs[i] = configs[i].redacted()
+	}
 	return Configs{Data: configs}, err
 }
 
-// Create creates a new config.
+// StartAll starts scrapers for all existing configs. First scrapes are spread
+// over the scraping interval, so that a restart does not scrape all the
+// targets at once. Configs that cannot be started are logged and skipped. In
+// a cluster it leases the configs and keeps reconciling the scrapers with the
+// leased configs until shutdown.
+func (s *Service) StartAll() error {
+	if s.membership != nil {
+		if err := s.reconcile(); err != nil {
+			return err
+		}
+		s.coordinator.Add(1)
+		go s.coordinate()
+		return nil
+	}
+
+	configs, err := s.store.GetAll()
+	if err != nil {
+		return err
+	}
+
+	started := 0
+	for i, cfg := range configs {
+		target, err := cfg.target()
+		if err != nil {
+			log.Printf("skip config %s: %+v\n", cfg.Name, err)
+			continue
+		}
+
+		delay := target.Interval * time.Duration(i) / time.Duration(len(configs))
+		resCh, err := s.scraperManager.RunDelayed(cfg.Name, target, delay)
+		if err != nil {
+			log.Printf("skip config %s: %+v\n", cfg.Name, err)
+			continue
+		}
+		s.consume(cfg.Name, resCh)
+		started++
+	}
+
+	log.Printf("started %d of %d scrapers\n", started, len(configs))
+	return nil
+}
+
+// Create creates a new config and returns it without secrets.
 func (s *Service) Create(cfg Config) (Config, error) {
-	duration, err := time.ParseDuration(cfg.ScrapingInterval)
+	target, err := cfg.target()
 	if err != nil {
-		return Config{},
-			errors.Wrapf(
-				err, "failed to parse scraping interval %q",
-				cfg.ScrapingInterval,
-			)
+		return Config{}, err
+	}
+	if err = s.scraperManager.Validate(target); err != nil {
+		return Config{}, err
 	}
 
 	cfg, err = s.store.Create(cfg)
 	if err != nil {
 		return cfg, err
 	}
+	if s.membership != nil {
+		s.tryReconcile()
+		return cfg.redacted(), nil
+	}
 
-	resCh, err := s.scraperManager.Run(cfg.Name, cfg.URL, duration)
+	resCh, err := s.scraperManager.Run(cfg.Name, target)
 	if err != nil {
 		return Config{}, err
 	}
-	go s.metricService.Consume(cfg.Name, resCh)
-	return cfg, nil
+	s.consume(cfg.Name, resCh)
+	return cfg.redacted(), nil
 }
 
-// Get returns a config with the given name.
+// Get returns a config with the given name without secrets.
 func (s *Service) Get(name string) (Config, error) {
 	cfg, err := s.store.Get(name)
 	if err != nil {
 		return Config{}, err
 	}
-	return cfg, nil
+	return cfg.redacted(), nil
 }
 
 // Update updates a config with the given name.
 func (s *Service) Update(cfg Config) error {
-	duration, err := time.ParseDuration(cfg.ScrapingInterval)
+	target, err := cfg.target()
 	if err != nil {
-		return errors.Wrapf(
-			err, "failed to parse scraping interval %q", cfg.ScrapingInterval,
-		)
+		return err
+	}
+	if err = s.scraperManager.Validate(target); err != nil {
+		return err
 	}
 
 	cfg, err = s.store.Update(cfg)
 	if err != nil {
 		return err
 	}
+	if s.membership != nil {
+		s.tryReconcile()
+		return nil
+	}
 
-	resCh, err := s.scraperManager.Update(cfg.Name, cfg.URL, duration)
+	resCh, err := s.scraperManager.Update(cfg.Name, target)
 	if err != nil {
 		return err
 	}
-	go s.metricService.Consume(cfg.Name, resCh)
+	s.consume(cfg.Name, resCh)
 	return nil
 }
 
@@ -138,6 +276,10 @@ func (s *Service) Delete(name string) error {
 	if err != nil {
 		return err
 	}
+	if s.membership != nil {
+		s.tryReconcile()
+		return nil
+	}
 
 	err = s.scraperManager.Stop(name)
 	if err != nil {
@@ -147,3 +289,366 @@ func (s *Service) Delete(name string) error {
 
 	return nil
 }
+
+// Shutdown stops all scrapers and waits for the consumers to store their
+// results. It returns an error if the context is done first. In a cluster the
+// instance leaves it afterwards, so that other instances take its configs
+// over.
+func (s *Service) Shutdown(ctx context.Context) error {
+	if s.membership != nil {
+		s.stopOnce.Do(func() {
+			close(s.stopCh)
+		})
+		s.coordinator.Wait()
+		s.leaseMu.Lock()
+		if s.expiry != nil {
+			s.expiry.Stop()
+		}
+		s.leaseMu.Unlock()
+		defer func() {
+			if err := s.membership.Leave(); err != nil {
+				log.Printf("failed to leave the cluster: %+v\n", err)
+			}
+		}()
+	}
+
+	err := s.scraperManager.Shutdown(ctx)
+	if err != nil {
+		log.Printf("failed to stop scrapers gracefully: %+v\n", err)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		s.consumers.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return err
+	case <-ctx.Done():
+		return errors.Wrap(ctx.Err(), "failed to wait for metric consumers")
+	}
+}
+
+// coordinate reconciles the scrapers with the leased configs every third of
+// the lease TTL until shutdown.
+func (s *Service) coordinate() {
+	defer s.coordinator.Done()
+
+	t := time.NewTicker(s.ttl / 3)
+	defer t.Stop()
+	for {
+		select {
+		case <-t.C:
+			s.tryReconcile()
+		case <-s.stopCh:
+			return
+		}
+	}
+}
+
+// tryReconcile reconciles the scrapers and logs the error if any.
+func (s *Service) tryReconcile() {
+	if err := s.reconcile(); err != nil {
+		log.Printf("failed to reconcile scrapers: %+v\n", err)
+	}
+}
+
+// reconcile runs scrapers for the configs leased to the instance: scrapers
+// of new configs are started, scrapers of changed configs are updated and
+// scrapers of configs that are no longer leased are stopped. Leases of the
+// configs assigned to other instances are released once the results of their
+// stopped scrapers are consumed, so that a config is never scraped by two
+// instances. If the leases cannot be renewed all scrapers are stopped, since
+// other instances may take the configs over. Expiry stops them as well when
+// the leases are not renewed in time, even if the reconciliation is stuck.
+func (s *Service) reconcile() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	select {
+	case <-s.stopCh:
+		return nil
+	default:
+	}
+
+	fail := func(err error) error {
+		s.leaseMu.Lock()
+		defer s.leaseMu.Unlock()
+		s.stopAll()
+		return err
+	}
+
+	configs, err := s.store.GetAll()
+	if err != nil {
+		return fail(err)
+	}
+	names := make([]string, len(configs))
+	for i, cfg := range configs {
+		names[i] = cfg.Name
+	}
+
+	// 1. Stop the scrapers of the configs that are not assigned anymore
+	assigned, err := s.membership.Assign(names)
+	if err != nil {
+		return fail(err)
+	}
+	s.leaseMu.Lock()
+	s.stopExcept(assigned)
+	s.leaseMu.Unlock()
+
+	// 2. Acquire the leases of the assigned configs and run their scrapers
+	acquiredAt := time.Now()
+	leased, err := s.membership.Acquire(assigned)
+	if err != nil {
+		return fail(err)
+	}
+	stopping, err := s.run(configs, leased, acquiredAt)
+	if err != nil {
+		return err
+	}
+
+	// 3. Release the leases of the configs whose scrapers are stopped and
+	// whose results are consumed
+	return s.membership.Release(append(assigned, stopping...))
+}
+
+// run renews the lease expiry and runs the scrapers of the leased configs.
+// It returns the names of the configs whose stopped scrapers still have
+// pending results.
+func (s *Service) run(
+	configs []Config, leased []string, acquiredAt time.Time,
+) ([]string, error) {
+	s.leaseMu.Lock()
+	defer s.leaseMu.Unlock()
+
+	s.renew(acquiredAt.Add(s.ttl - s.ttl/3))
+	if !time.Now().Before(s.leasedUntil) {
+		s.stopAll()
+		return nil, errors.New("leases are acquired after they are expired")
+	}
+	s.stopExcept(leased)
+
+	held := make(map[string]bool, len(leased))
+	for _, name := range leased {
+		held[name] = true
+	}
+	for _, cfg := range configs {
+		running, exists := s.running[cfg.Name]
+		if !held[cfg.Name] || exists && reflect.DeepEqual(running.cfg, cfg) {
+			continue
+		}
+		target, err := cfg.target()
+		if err != nil {
+			log.Printf("skip config %s: %+v\n", cfg.Name, err)
+			continue
+		}
+
+		var resCh <-chan scrape.Result
+		if exists {
+			resCh, err = s.scraperManager.Update(cfg.Name, target)
+		} else {
+			resCh, err = s.scraperManager.Run(cfg.Name, target)
+		}
+		if err != nil {
+			log.Printf("skip config %s: %+v\n", cfg.Name, err)
+			continue
+		}
+		running.cfg = cfg
+		running.add(s.consume(cfg.Name, resCh))
+		s.running[cfg.Name] = running
+	}
+
+	stopping := make([]string, 0, len(s.stopping))
+	for name, consumed := range s.stopping {
+		if consumed = pending(consumed); len(consumed) > 0 {
+			s.stopping[name] = consumed
+			stopping = append(stopping, name)
+		} else {
+			delete(s.stopping, name)
+		}
+	}
+	return stopping, nil
+}
+
+// renew sets the time the scrapers are stopped at unless the leases are
+// renewed again. The caller must hold the lease lock.
+func (s *Service) renew(until time.Time) {
+	s.leasedUntil = until
+	if s.expiry == nil {
+		s.expiry = time.AfterFunc(time.Until(until), s.expire)
+		return
+	}
+	s.expiry.Reset(time.Until(until))
+}
+
+// expire stops all scrapers run in the cluster unless the leases have been
+// renewed in the meantime.
+func (s *Service) expire() {
+	s.leaseMu.Lock()
+	defer s.leaseMu.Unlock()
+
+	if time.Now().Before(s.leasedUntil) {
+		return
+	}
+	if len(s.running) > 0 {
+		log.Printf("leases are not renewed in time, stop %d scrapers\n", len(s.running))
+	}
+	s.stopAll()
+}
+
+// stopExcept stops the scrapers run in the cluster of all configs but the
+// ones with the given names. The caller must hold the lease lock.
+func (s *Service) stopExcept(names []string) {
+	keep := make(map[string]bool, len(names))
+	for _, name := range names {
+		keep[name] = true
+	}
+	for name := range s.running {
+		if !keep[name] {
+			s.stop(name)
+		}
+	}
+}
+
+// stopAll stops all scrapers run in the cluster. The caller must hold the
+// lease lock.
+func (s *Service) stopAll() {
+	for name := range s.running {
+		s.stop(name)
+	}
+}
+
+// stop stops the scraper of the config with the given name run in the
+// cluster. It does not wait for the last scrape to be consumed, the consumers
+// are tracked until then to keep the lease of the config. The caller must
+// hold the lease lock.
+func (s *Service) stop(name string) {
+	if err := s.scraperManager.Stop(name); err != nil {
+		log.Printf("%+v\n", err)
+	}
+	s.stopping[name] = append(s.stopping[name], s.running[name].consumed...)
+	delete(s.running, name)
+}
+
+// consume consumes the scrape results of the config in a tracked goroutine.
+// The returned channel is closed when the result channel is closed and all
+// results are consumed.
+func (s *Service) consume(
+	name string, resCh <-chan scrape.Result,
+) <-chan struct{} {
+	done := make(chan struct{})
+	s.consumers.Add(1)
+	go func() {
+		defer s.consumers.Done()
+		defer close(done)
+		s.metricService.Consume(name, resCh)
+	}()
+	return done
+}
+
+// runningConfig is a config scrapers are run for in the cluster.
+type runningConfig struct {
+	cfg Config
+	// consumed contains the channels closed when the consumers of the
+	// scrapers of the config, the updated ones included, are finished.
+	consumed []<-chan struct{}
+}
+
+// add tracks the consumer of a new scraper of the config and forgets the
+// finished consumers.
+func (r *runningConfig) add(consumed <-chan struct{}) {
+	r.consumed = append(pending(r.consumed), consumed)
+}
+
+// pending returns the channels of the given ones that are not closed, i.e.
+// the consumers that are not finished.
+func pending(consumed []<-chan struct{}) []<-chan struct{} {
+	res := consumed[:0]
+	for _, done := range consumed {
+		select {
+		case <-done:
+		default:
+			res = append(res, done)
+		}
+	}
+	return res
+}
+
+// redacted returns a copy of the config without auth secrets and values of
+// headers carrying credentials.
+func (c Config) redacted() Config {
+	if c.Auth != nil {
+		auth := c.Auth.Redacted()
+		c.Auth = &auth
+	}
+	c.Headers = scrape.RedactHeaders(c.Headers)
+	return c
+}
+
+// target validates the config and returns the scrape target described by it.
+func (c Config) target() (scrape.Target, error) {
+	duration, err := parseScrapingInterval(c.ScrapingInterval)
+	if err != nil {
+		return scrape.Target{}, err
+	}
+	timeout, err := parseTimeout(c.Timeout)
+	if err != nil {
+		return scrape.Target{}, err
+	}
+
+	target := scrape.Target{
+		Kind:           c.Kind,
+		URL:            c.URL,
+		Interval:       duration,
+		Timeout:        timeout,
+		Settings:       c.Settings,
+		Method:         c.Method,
+		Headers:        c.Headers,
+		Body:           c.Body,
+		Auth:           c.Auth,
+		RedirectPolicy: c.RedirectPolicy,
+		Assertions:     c.Assertions,
+		Script:         c.Script,
+		Retry:          c.Retry,
+		Failing:        c.Failing,
+	}
+	if err = target.Validate(); err != nil {
+		return scrape.Target{}, err
+	}
+	return target, nil
+}
+
+// parseScrapingInterval parses the given scraping interval. Only positive
+// intervals are valid.
+func parseScrapingInterval(interval string) (time.Duration, error) {
+	duration, err := time.ParseDuration(interval)
+	if err != nil {
+		return 0, errors.Wrapf(
+			err, "failed to parse scraping interval %q", interval,
+		)
+	}
+	if duration <= 0 {
+		return 0, errors.Errorf(
+			"scraping interval %q must be positive", interval,
+		)
+	}
+	return duration, nil
+}
+
+// parseTimeout parses the given scrape timeout. An empty timeout means no
+// timeout, otherwise only positive timeouts are valid.
+func parseTimeout(timeout string) (time.Duration, error) {
+	if timeout == "" {
+		return 0, nil
+	}
+	duration, err := time.ParseDuration(timeout)
+	if err != nil {
+		return 0, errors.Wrapf(err, "failed to parse timeout %q", timeout)
+	}
+	if duration <= 0 {
+		return 0, errors.Errorf("timeout %q must be positive", timeout)
+	}
+	return duration, nil
+}
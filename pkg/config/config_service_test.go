@@ -1,16 +1,24 @@
 package config
 
 import (
+	"context"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 
 	"github.com/mneverov/webapp101/pkg/scrape"
 )
 
-var testScrapingInterval = 42 * time.Second
+var (
+	testScrapingInterval = 42 * time.Second
+	testTarget           = scrape.Target{
+		URL:      testCfg.URL,
+		Interval: testScrapingInterval,
+	}
+)
 
 func TestConfigService_GetAll(t *testing.T) {
 	t.Run("should propagate error from DB", func(t *testing.T) {
@@ -37,6 +45,101 @@ func TestConfigService_GetAll(t *testing.T) {
 		assert.Equal(t, testCfg, res.Data[0])
 		ts.db.AssertExpectations(t)
 	})
+
+	t.Run("should not return auth secrets", func(t *testing.T) {
+		ts := createTestServices()
+		cfg := testCfg
+		cfg.Auth = &scrape.Auth{
+			Type:     scrape.AuthBasic,
+			Username: "user",
+			Password: "secret",
+		}
+		ts.db.On("GetAll").
+			Return([]Config{cfg}, nil).
+			Once()
+
+		res, err := ts.cfgService.GetAll()
+		assert.NoError(t, err)
+		require.Len(t, res.Data, 1)
+		assert.Equal(t,
+			&scrape.Auth{Type: scrape.AuthBasic, Username: "user"},
+			res.Data[0].Auth,
+		)
+		// the stored config is not modified.
+		assert.Equal(t, "secret", cfg.Auth.Password)
+		ts.db.AssertExpectations(t)
+	})
+
+	t.Run("should not return values of credential headers", func(t *testing.T) {
+		ts := createTestServices()
+		cfg := testCfg
+		cfg.Headers = map[string]string{
+			"Accept":        "application/json",
+			"Authorization": "Bearer secret",
+		}
+		ts.db.On("GetAll").
+			Return([]Config{cfg}, nil).
+			Once()
+
+		res, err := ts.cfgService.GetAll()
+		assert.NoError(t, err)
+		require.Len(t, res.Data, 1)
+		assert.Equal(t,
+			map[string]string{"Accept": "application/json", "Authorization": ""},
+			res.Data[0].Headers,
+		)
+		assert.Equal(t, "Bearer secret", cfg.Headers["Authorization"])
+		ts.db.AssertExpectations(t)
+	})
+}
+
+func TestConfigService_StartAll(t *testing.T) {
+	t.Run("should propagate error from DB", func(t *testing.T) {
+		ts := createTestServices()
+		ts.db.On("GetAll").
+			Return(nil, assert.AnError).
+			Once()
+
+		err := ts.cfgService.StartAll()
+
+		require.Error(t, err)
+		assert.Equal(t, assert.AnError, err)
+		ts.db.AssertExpectations(t)
+	})
+
+	t.Run("should skip configs that fail to start", func(t *testing.T) {
+		ts := createTestServices()
+		invalidCfg := testCfg
+		invalidCfg.Name = "invalid_cfg"
+		invalidCfg.ScrapingInterval = "invalid_duration"
+		failingCfg := exampleCfg
+		failingCfg.ScrapingInterval = testCfg.ScrapingInterval
+		ch := make(<-chan scrape.Result)
+
+		ts.db.On("GetAll").
+			Return([]Config{invalidCfg, failingCfg, testCfg}, nil).
+			Once()
+
+		ts.scraperManager.
+			On("RunDelayed", failingCfg.Name,
+				scrape.Target{URL: failingCfg.URL, Interval: testScrapingInterval},
+				testScrapingInterval/3).
+			Return(nil, assert.AnError).
+			Once()
+		ts.scraperManager.
+			On("RunDelayed", testCfg.Name, testTarget,
+				2*testScrapingInterval/3).
+			Return(ch, nil).
+			Once()
+
+		ts.metricService.On("Consume", testCfg.Name, ch).Return()
+
+		err := ts.cfgService.StartAll()
+
+		assert.NoError(t, err)
+		ts.scraperManager.AssertExpectations(t)
+		ts.db.AssertExpectations(t)
+	})
 }
 
 func TestConfigService_Create(t *testing.T) {
@@ -51,6 +154,129 @@ func TestConfigService_Create(t *testing.T) {
 		assert.Regexp(t, testCfg.ScrapingInterval, err)
 	})
 
+	t.Run("should return error when interval is not positive", func(t *testing.T) {
+		ts := createTestServices()
+		testCfg := testCfg
+		testCfg.ScrapingInterval = "0s"
+
+		_, err := ts.cfgService.Create(testCfg)
+
+		require.Error(t, err)
+		assert.Regexp(t, "must be positive", err)
+	})
+
+	t.Run("should return error when timeout is invalid", func(t *testing.T) {
+		ts := createTestServices()
+		testCfg := testCfg
+		testCfg.Timeout = "-1s"
+
+		_, err := ts.cfgService.Create(testCfg)
+
+		require.Error(t, err)
+		assert.Regexp(t, "must be positive", err)
+	})
+
+	t.Run("should pass timeout to scraper", func(t *testing.T) {
+		ts := createTestServices()
+		cfg := testCfg
+		cfg.Timeout = "3s"
+		target := testTarget
+		target.Timeout = 3 * time.Second
+		ch := make(<-chan scrape.Result)
+
+		ts.db.On("Create", cfg).
+			Return(cfg, nil).
+			Once()
+		ts.scraperManager.
+			On("Run", cfg.Name, target).
+			Return(ch, nil).
+			Once()
+		ts.metricService.On("Consume", cfg.Name, ch).Return()
+
+		_, err := ts.cfgService.Create(cfg)
+
+		assert.NoError(t, err)
+		ts.scraperManager.AssertExpectations(t)
+		ts.db.AssertExpectations(t)
+	})
+
+	t.Run("should return error when assertion is invalid", func(t *testing.T) {
+		ts := createTestServices()
+		testCfg := testCfg
+		testCfg.Assertions = []scrape.Assertion{
+			{Type: scrape.AssertionRegex, Value: "(unclosed"},
+		}
+
+		_, err := ts.cfgService.Create(testCfg)
+
+		require.Error(t, err)
+		assert.Regexp(t, "invalid regex", err)
+	})
+
+	t.Run("should return error when script is invalid", func(t *testing.T) {
+		ts := createTestServices()
+		testCfg := testCfg
+		testCfg.Script = "response.status =="
+
+		_, err := ts.cfgService.Create(testCfg)
+
+		require.Error(t, err)
+		assert.Regexp(t, "invalid script", err)
+	})
+
+	t.Run("should return error when auth is invalid", func(t *testing.T) {
+		ts := createTestServices()
+		testCfg := testCfg
+		testCfg.Auth = &scrape.Auth{Type: scrape.AuthBearer}
+
+		_, err := ts.cfgService.Create(testCfg)
+
+		require.Error(t, err)
+		assert.Regexp(t, "requires a token", err)
+	})
+
+	t.Run("should return error when redirect policy is invalid", func(t *testing.T) {
+		ts := createTestServices()
+		testCfg := testCfg
+		testCfg.RedirectPolicy = &scrape.RedirectPolicy{Follow: true, MaxHops: -1}
+
+		_, err := ts.cfgService.Create(testCfg)
+
+		require.Error(t, err)
+		assert.Regexp(t, "max hops", err)
+	})
+
+	t.Run("should return error when method is invalid", func(t *testing.T) {
+		ts := createTestServices()
+		testCfg := testCfg
+		testCfg.Method = "FETCH"
+
+		_, err := ts.cfgService.Create(testCfg)
+
+		require.Error(t, err)
+		assert.Regexp(t, "unsupported method", err)
+	})
+
+	t.Run("should return error when probe kind is invalid", func(t *testing.T) {
+		ts := createTestServices()
+		scraperManager := &mockScraperManager{}
+		cfgService := NewService(ts.db, ts.metricService, scraperManager)
+		cfg := testCfg
+		cfg.Kind = "unknown"
+		target := testTarget
+		target.Kind = cfg.Kind
+		scraperManager.On("Validate", target).
+			Return(assert.AnError).
+			Once()
+
+		_, err := cfgService.Create(cfg)
+
+		require.Error(t, err)
+		assert.Equal(t, assert.AnError, err)
+		scraperManager.AssertExpectations(t)
+		ts.db.AssertExpectations(t)
+	})
+
 	t.Run("should return error on DB failure", func(t *testing.T) {
 		ts := createTestServices()
 		cfg := testCfg
@@ -74,7 +300,7 @@ func TestConfigService_Create(t *testing.T) {
 			Once()
 
 		ts.scraperManager.
-			On("Run", cfg.Name, cfg.URL, testScrapingInterval).
+			On("Run", cfg.Name, testTarget).
 			Return(nil, assert.AnError).
 			Once()
 
@@ -96,7 +322,7 @@ func TestConfigService_Create(t *testing.T) {
 			Once()
 
 		ts.scraperManager.
-			On("Run", cfg.Name, cfg.URL, testScrapingInterval).
+			On("Run", cfg.Name, testTarget).
 			Return(ch, nil).
 			Once()
 
@@ -117,6 +343,18 @@ func TestConfigService_Create(t *testing.T) {
 // TestConfigService_Update only tests happy path. The rest of the tests may
 // be added by participants.
 func TestConfigService_Update(t *testing.T) {
+	t.Run("should return error when header is invalid", func(t *testing.T) {
+		ts := createTestServices()
+		cfg := testCfg
+		cfg.Headers = map[string]string{"Bad Header": "value"}
+
+		err := ts.cfgService.Update(cfg)
+
+		require.Error(t, err)
+		assert.Regexp(t, "invalid header name", err)
+		ts.db.AssertExpectations(t)
+	})
+
 	t.Run("should update existing config", func(t *testing.T) {
 		ts := createTestServices()
 		cfg := testCfg
@@ -127,7 +365,7 @@ func TestConfigService_Update(t *testing.T) {
 			Once()
 
 		ts.scraperManager.
-			On("Update", cfg.Name, cfg.URL, testScrapingInterval).
+			On("Update", cfg.Name, testTarget).
 			Return(ch, nil).
 			Once()
 
@@ -144,11 +382,395 @@ func TestConfigService_Update(t *testing.T) {
 	})
 }
 
+func TestConfigService_Shutdown(t *testing.T) {
+	t.Run("should wait for consumers after stopping scrapers", func(t *testing.T) {
+		ts := createTestServices()
+		cfg := testCfg
+		resCh := make(chan scrape.Result)
+		consumed := make(chan struct{})
+
+		ts.db.On("Create", cfg).
+			Return(cfg, nil).
+			Once()
+		ts.scraperManager.
+			On("Run", cfg.Name, testTarget).
+			Return((<-chan scrape.Result)(resCh), nil).
+			Once()
+		ts.metricService.On("Consume", cfg.Name, (<-chan scrape.Result)(resCh)).
+			Run(func(args mock.Arguments) {
+				for range args.Get(1).(<-chan scrape.Result) {
+				}
+				close(consumed)
+			}).
+			Return()
+		ts.scraperManager.On("Shutdown", mock.Anything).
+			Run(func(mock.Arguments) { close(resCh) }).
+			Return(nil).
+			Once()
+		_, err := ts.cfgService.Create(cfg)
+		require.NoError(t, err)
+
+		err = ts.cfgService.Shutdown(context.Background())
+
+		assert.NoError(t, err)
+		select {
+		case <-consumed:
+		default:
+			t.Fatal("consumer is not finished")
+		}
+		ts.scraperManager.AssertExpectations(t)
+	})
+
+	t.Run("should return error when consumers are not finished in time", func(t *testing.T) {
+		ts := createTestServices()
+		cfg := testCfg
+		resCh := make(chan scrape.Result)
+		defer close(resCh)
+
+		ts.db.On("Create", cfg).
+			Return(cfg, nil).
+			Once()
+		ts.scraperManager.
+			On("Run", cfg.Name, testTarget).
+			Return((<-chan scrape.Result)(resCh), nil).
+			Once()
+		ts.metricService.On("Consume", cfg.Name, (<-chan scrape.Result)(resCh)).
+			Run(func(args mock.Arguments) {
+				for range args.Get(1).(<-chan scrape.Result) {
+				}
+			}).
+			Return()
+		ts.scraperManager.On("Shutdown", mock.Anything).
+			Return(nil).
+			Once()
+		_, err := ts.cfgService.Create(cfg)
+		require.NoError(t, err)
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+
+		err = ts.cfgService.Shutdown(ctx)
+
+		require.Error(t, err)
+		assert.Regexp(t, "metric consumers", err)
+	})
+}
+
+func TestConfigService_Reconcile(t *testing.T) {
+	otherCfg := testCfg
+	otherCfg.Name = "other"
+	resCh := make(<-chan scrape.Result)
+
+	t.Run("should run scrapers only for leased configs", func(t *testing.T) {
+		ts := createClusterTestServices()
+		ts.db.On("GetAll").
+			Return([]Config{testCfg, otherCfg}, nil).
+			Once()
+		ts.membership.On("Assign", []string{testCfg.Name, otherCfg.Name}).
+			Return([]string{testCfg.Name, otherCfg.Name}, nil).
+			Once()
+		ts.membership.On("Release", []string{testCfg.Name, otherCfg.Name}).
+			Return(nil).
+			Once()
+		ts.membership.On("Acquire", []string{testCfg.Name, otherCfg.Name}).
+			Return([]string{testCfg.Name}, nil).
+			Once()
+		ts.scraperManager.On("Run", testCfg.Name, testTarget).
+			Return(resCh, nil).
+			Once()
+		ts.metricService.On("Consume", testCfg.Name, resCh).
+			Return().
+			Once()
+
+		err := ts.cfgService.reconcile()
+
+		assert.NoError(t, err)
+		ts.membership.AssertExpectations(t)
+		ts.scraperManager.AssertExpectations(t)
+	})
+
+	t.Run("should update changed and stop unassigned scrapers", func(t *testing.T) {
+		ts := createClusterTestServices()
+		ts.cfgService.running[testCfg.Name] = runningConfig{cfg: testCfg}
+		ts.cfgService.running[otherCfg.Name] = runningConfig{cfg: otherCfg}
+		changedCfg := testCfg
+		changedCfg.ScrapingInterval = "1m"
+		changedTarget := testTarget
+		changedTarget.Interval = time.Minute
+
+		ts.db.On("GetAll").
+			Return([]Config{changedCfg, otherCfg}, nil).
+			Once()
+		ts.membership.On("Assign", []string{testCfg.Name, otherCfg.Name}).
+			Return([]string{testCfg.Name}, nil).
+			Once()
+		ts.membership.On("Release", []string{testCfg.Name}).
+			Return(nil).
+			Once()
+		ts.membership.On("Acquire", []string{testCfg.Name}).
+			Return([]string{testCfg.Name}, nil).
+			Once()
+		ts.scraperManager.On("Update", testCfg.Name, changedTarget).
+			Return(resCh, nil).
+			Once()
+		ts.metricService.On("Consume", testCfg.Name, resCh).
+			Return().
+			Once()
+		ts.scraperManager.On("Stop", otherCfg.Name).
+			Return(nil).
+			Once()
+
+		err := ts.cfgService.reconcile()
+
+		assert.NoError(t, err)
+		require.Len(t, ts.cfgService.running, 1)
+		assert.Equal(t, changedCfg, ts.cfgService.running[testCfg.Name].cfg)
+		ts.scraperManager.AssertExpectations(t)
+	})
+
+	t.Run("should not restart unchanged scrapers", func(t *testing.T) {
+		ts := createClusterTestServices()
+		ts.cfgService.running[testCfg.Name] = runningConfig{cfg: testCfg}
+		ts.db.On("GetAll").
+			Return([]Config{testCfg}, nil).
+			Once()
+		ts.membership.On("Assign", []string{testCfg.Name}).
+			Return([]string{testCfg.Name}, nil).
+			Once()
+		ts.membership.On("Release", []string{testCfg.Name}).
+			Return(nil).
+			Once()
+		ts.membership.On("Acquire", []string{testCfg.Name}).
+			Return([]string{testCfg.Name}, nil).
+			Once()
+
+		err := ts.cfgService.reconcile()
+
+		assert.NoError(t, err)
+		ts.scraperManager.AssertNotCalled(t, "Run", mock.Anything, mock.Anything)
+		ts.scraperManager.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+	})
+
+	t.Run("should keep leases of stopped scrapers until results are consumed", func(t *testing.T) {
+		ts := createClusterTestServices()
+		ch := make(chan scrape.Result)
+		proceed := make(chan struct{})
+		consumed := make(chan struct{})
+
+		ts.db.On("GetAll").
+			Return([]Config{testCfg}, nil)
+		ts.membership.On("Assign", []string{testCfg.Name}).
+			Return([]string{testCfg.Name}, nil).
+			Once()
+		ts.membership.On("Acquire", []string{testCfg.Name}).
+			Return([]string{testCfg.Name}, nil).
+			Once()
+		ts.membership.On("Release", []string{testCfg.Name}).
+			Return(nil).
+			Once()
+		ts.scraperManager.On("Run", testCfg.Name, testTarget).
+			Return((<-chan scrape.Result)(ch), nil).
+			Once()
+		ts.metricService.On("Consume", testCfg.Name, (<-chan scrape.Result)(ch)).
+			Run(func(args mock.Arguments) {
+				for range args.Get(1).(<-chan scrape.Result) {
+				}
+				<-proceed
+				close(consumed)
+			}).
+			Return().
+			Once()
+		require.NoError(t, ts.cfgService.reconcile())
+
+		// the scraper is stopped, its last result is not consumed yet.
+		ts.membership.On("Assign", []string{testCfg.Name}).
+			Return([]string{}, nil).
+			Once()
+		ts.scraperManager.On("Stop", testCfg.Name).
+			Run(func(mock.Arguments) { close(ch) }).
+			Return(nil).
+			Once()
+		ts.membership.On("Acquire", []string{}).
+			Return([]string{}, nil).
+			Once()
+		ts.membership.On("Release", []string{testCfg.Name}).
+			Return(nil).
+			Once()
+		require.NoError(t, ts.cfgService.reconcile())
+		assert.Empty(t, ts.cfgService.running)
+
+		// the result is consumed.
+		close(proceed)
+		<-consumed
+		ts.membership.On("Assign", []string{testCfg.Name}).
+			Return([]string{}, nil).
+			Once()
+		ts.membership.On("Acquire", []string{}).
+			Return([]string{}, nil).
+			Once()
+		ts.membership.On("Release", []string{}).
+			Return(nil).
+			Once()
+
+		err := ts.cfgService.reconcile()
+
+		assert.NoError(t, err)
+		assert.Empty(t, ts.cfgService.stopping)
+		ts.membership.AssertExpectations(t)
+		ts.scraperManager.AssertExpectations(t)
+	})
+
+	t.Run("should stop scrapers when leases are not renewed in time", func(t *testing.T) {
+		ts := createClusterTestServices()
+		ts.cfgService.ttl = 30 * time.Millisecond
+		ts.cfgService.running[testCfg.Name] = runningConfig{cfg: testCfg}
+		ts.db.On("GetAll").
+			Return([]Config{testCfg}, nil).
+			Once()
+		ts.membership.On("Assign", []string{testCfg.Name}).
+			Return([]string{testCfg.Name}, nil).
+			Once()
+		ts.membership.On("Acquire", []string{testCfg.Name}).
+			Return([]string{testCfg.Name}, nil).
+			Once()
+		ts.membership.On("Release", []string{testCfg.Name}).
+			Return(nil).
+			Once()
+		require.NoError(t, ts.cfgService.reconcile())
+
+		// the next reconciliation is stuck.
+		stuck := make(chan struct{})
+		ts.db.On("GetAll").
+			Run(func(mock.Arguments) { <-stuck }).
+			Return([]Config{testCfg}, nil).
+			Once()
+		ts.membership.On("Assign", []string{testCfg.Name}).
+			Return(nil, assert.AnError).
+			Once()
+		stopped := make(chan struct{})
+		ts.scraperManager.On("Stop", testCfg.Name).
+			Run(func(mock.Arguments) { close(stopped) }).
+			Return(nil).
+			Once()
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			_ = ts.cfgService.reconcile()
+		}()
+
+		select {
+		case <-stopped:
+		case <-time.After(time.Second):
+			t.Error("scraper is not stopped")
+		}
+		close(stuck)
+		<-done
+		assert.Empty(t, ts.cfgService.running)
+	})
+
+	t.Run("should stop all scrapers when failed to assign configs", func(t *testing.T) {
+		ts := createClusterTestServices()
+		ts.cfgService.running[testCfg.Name] = runningConfig{cfg: testCfg}
+		ts.db.On("GetAll").
+			Return([]Config{testCfg}, nil).
+			Once()
+		ts.membership.On("Assign", []string{testCfg.Name}).
+			Return(nil, assert.AnError).
+			Once()
+		ts.scraperManager.On("Stop", testCfg.Name).
+			Return(nil).
+			Once()
+
+		err := ts.cfgService.reconcile()
+
+		assert.Equal(t, assert.AnError, err)
+		assert.Empty(t, ts.cfgService.running)
+		ts.scraperManager.AssertExpectations(t)
+	})
+
+	t.Run("should stop all scrapers when failed to renew leases", func(t *testing.T) {
+		ts := createClusterTestServices()
+		ts.cfgService.running[testCfg.Name] = runningConfig{cfg: testCfg}
+		ts.db.On("GetAll").
+			Return([]Config{testCfg}, nil).
+			Once()
+		ts.membership.On("Assign", []string{testCfg.Name}).
+			Return([]string{testCfg.Name}, nil).
+			Once()
+		ts.membership.On("Acquire", []string{testCfg.Name}).
+			Return(nil, assert.AnError).
+			Once()
+		ts.scraperManager.On("Stop", testCfg.Name).
+			Return(nil).
+			Once()
+
+		err := ts.cfgService.reconcile()
+
+		assert.Equal(t, assert.AnError, err)
+		assert.Empty(t, ts.cfgService.running)
+		ts.scraperManager.AssertExpectations(t)
+	})
+
+	t.Run("should not run scraper of created config assigned to other instance", func(t *testing.T) {
+		ts := createClusterTestServices()
+		ts.db.On("Create", testCfg).
+			Return(testCfg, nil).
+			Once()
+		ts.db.On("GetAll").
+			Return([]Config{testCfg}, nil).
+			Once()
+		ts.membership.On("Assign", []string{testCfg.Name}).
+			Return([]string{}, nil).
+			Once()
+		ts.membership.On("Release", []string{}).
+			Return(nil).
+			Once()
+		ts.membership.On("Acquire", []string{}).
+			Return([]string{}, nil).
+			Once()
+
+		_, err := ts.cfgService.Create(testCfg)
+
+		assert.NoError(t, err)
+		ts.membership.AssertExpectations(t)
+		ts.scraperManager.AssertNotCalled(t, "Run", mock.Anything, mock.Anything)
+	})
+
+	t.Run("should leave cluster on shutdown", func(t *testing.T) {
+		ts := createClusterTestServices()
+		ts.db.On("GetAll").
+			Return([]Config{}, nil).
+			Once()
+		ts.membership.On("Assign", []string{}).
+			Return([]string{}, nil).
+			Once()
+		ts.membership.On("Release", []string{}).
+			Return(nil).
+			Once()
+		ts.membership.On("Acquire", []string{}).
+			Return([]string{}, nil).
+			Once()
+		ts.scraperManager.On("Shutdown", mock.Anything).
+			Return(nil).
+			Once()
+		ts.membership.On("Leave").
+			Return(nil).
+			Once()
+		require.NoError(t, ts.cfgService.StartAll())
+
+		err := ts.cfgService.Shutdown(context.Background())
+
+		assert.NoError(t, err)
+		ts.membership.AssertExpectations(t)
+		ts.scraperManager.AssertExpectations(t)
+	})
+}
+
 type ts struct {
 	cfgService     *Service
 	metricService  *mockMetricService
 	scraperManager *mockScraperManager
 	db             *mockConfigStore
+	membership     *mockMembership
 }
 
 func createTestServices() *ts {
@@ -156,11 +778,31 @@ func createTestServices() *ts {
 	scraperManager := &mockScraperManager{}
 	metricService := &mockMetricService{}
 	cfgService := NewService(db, metricService, scraperManager)
+	scraperManager.On("Validate", mock.Anything).Return(nil).Maybe()
+
+	return &ts{
+		cfgService:     cfgService,
+		metricService:  metricService,
+		scraperManager: scraperManager,
+		db:             db,
+	}
+}
+
+func createClusterTestServices() *ts {
+	db := &mockConfigStore{}
+	scraperManager := &mockScraperManager{}
+	metricService := &mockMetricService{}
+	membership := &mockMembership{}
+	cfgService := NewClusterService(
+		db, metricService, scraperManager, membership, time.Hour,
+	)
+	scraperManager.On("Validate", mock.Anything).Return(nil).Maybe()
 
 	return &ts{
 		cfgService:     cfgService,
 		metricService:  metricService,
 		scraperManager: scraperManager,
 		db:             db,
+		membership:     membership,
 	}
 }
@@ -1,12 +1,14 @@
 package config
 
 import (
+	"fmt"
 	"testing"
 
 	_ "github.com/lib/pq"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	"github.com/mneverov/webapp101/pkg/scrape"
 	"github.com/mneverov/webapp101/pkg/testutil"
 )
 
@@ -50,6 +52,57 @@ func TestConfigDB_Create(t *testing.T) {
 		assert.NoError(t, err)
 		assert.Equal(t, testCfg, res)
 	})
+
+	t.Run("should store config request, auth and assertions", func(t *testing.T) {
+		cfg := testCfg
+		cfg.Name = "test_cfg_assertions"
+		cfg.URL = "test_url_assertions"
+		cfg.Method = "POST"
+		cfg.Headers = map[string]string{"Accept": "application/json"}
+		cfg.Body = `{"check":"deep"}`
+		cfg.Auth = &scrape.Auth{Type: scrape.AuthBearer, Token: "token"}
+		cfg.RedirectPolicy = &scrape.RedirectPolicy{Follow: true, MaxHops: 3}
+		cfg.Assertions = []scrape.Assertion{
+			{Type: scrape.AssertionContains, Value: "ok"},
+			{Type: scrape.AssertionHeaderEquals, Header: "X-Status", Value: "up"},
+		}
+
+		_, err := db.Create(cfg)
+		require.NoError(t, err)
+
+		res, err := db.Get(cfg.Name)
+		assert.NoError(t, err)
+		assert.Equal(t, cfg, res)
+	})
+
+	t.Run("should store config kind and settings", func(t *testing.T) {
+		cfg := testCfg
+		cfg.Name = "test_cfg_kind"
+		cfg.URL = "example.com:443"
+		cfg.Kind = "test"
+		cfg.Settings = []byte(`{"timeout":"1s"}`)
+
+		_, err := db.Create(cfg)
+		require.NoError(t, err)
+
+		res, err := db.Get(cfg.Name)
+		require.NoError(t, err)
+		assert.Equal(t, cfg.Kind, res.Kind)
+		assert.JSONEq(t, string(cfg.Settings), string(res.Settings))
+	})
+
+	t.Run("should create configs with the same url", func(t *testing.T) {
+		for i, recordType := range []string{"A", "MX"} {
+			cfg := testCfg
+			cfg.Name = fmt.Sprintf("test_cfg_dns_%d", i)
+			cfg.URL = "example.com"
+			cfg.Kind = "dns"
+			cfg.Settings = []byte(fmt.Sprintf(`{"record_type":%q}`, recordType))
+
+			_, err := db.Create(cfg)
+			assert.NoError(t, err)
+		}
+	})
 }
 
 func TestConfigDB_Get(t *testing.T) {
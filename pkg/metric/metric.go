@@ -10,13 +10,36 @@ import (
 )
 
 // Metric represents a single web page metric, gathered with a scraper.
+// A metric of a failed scrape has the failure outcome, the error kind and the
+// error message. The response time is broken down into the request phases.
+// Redirects contains the followed redirect chain and Assertions contains the
+// results of the config assertions, Script contains the result of the config
+// script check. Details contains the kind-specific details of probes other
+// than http. Attempts contains the attempts of a retried scrape. A throttled
+// metric means the target rate limited the scraper.
 type Metric struct {
-	ID                int       `json:"-"                    pg:"id,pk"`
-	Name              string    `json:"-"                    pg:"name,use_zero"`
-	StatusCode        int       `json:"status_code"          pg:"status_code,use_zero"`
-	ResponseSizeBytes int64     `json:"response_size_bytes"  pg:"response_size,use_zero"`
-	ResponseTimeMs    int       `json:"response_time_ms"     pg:"response_time,use_zero"`
-	CreatedAt         time.Time `json:"created_at"           pg:"created_at"`
+	ID                int                      `json:"-"                        pg:"id,pk"`
+	Name              string                   `json:"-"                        pg:"name,use_zero"`
+	Outcome           string                   `json:"outcome"                  pg:"outcome,use_zero"`
+	ErrorKind         string                   `json:"error_kind,omitempty"     pg:"error_kind,use_zero"`
+	ErrorMessage      string                   `json:"error_message,omitempty"  pg:"error_message,use_zero"`
+	StatusCode        int                      `json:"status_code"              pg:"status_code,use_zero"`
+	ResponseSizeBytes int64                    `json:"response_size_bytes"      pg:"response_size,use_zero"`
+	ResponseTimeMs    int                      `json:"response_time_ms"         pg:"response_time,use_zero"`
+	DNSTimeMs         int                      `json:"dns_time_ms"              pg:"dns_time,use_zero"`
+	ConnectTimeMs     int                      `json:"connect_time_ms"          pg:"connect_time,use_zero"`
+	TLSTimeMs         int                      `json:"tls_time_ms"              pg:"tls_time,use_zero"`
+	TTFBMs            int                      `json:"ttfb_ms"                  pg:"ttfb,use_zero"`
+	TransferTimeMs    int                      `json:"transfer_time_ms"         pg:"transfer_time,use_zero"`
+	ConnReused        bool                     `json:"conn_reused"              pg:"conn_reused,use_zero"`
+	Redirects         []scrape.Redirect        `json:"redirects,omitempty"      pg:"redirects"`
+	Assertions        []scrape.AssertionResult `json:"assertions,omitempty"     pg:"assertions"`
+	Script            *scrape.ScriptResult     `json:"script,omitempty"         pg:"script"`
+	Attempts          []scrape.Attempt         `json:"attempts,omitempty"       pg:"attempts"`
+	Throttled         bool                     `json:"throttled"                pg:"throttled,use_zero"`
+	RetryAfterMs      int                      `json:"retry_after_ms,omitempty" pg:"retry_after,use_zero"`
+	Details           *scrape.Details          `json:"details,omitempty"        pg:"details"`
+	CreatedAt         time.Time                `json:"created_at"               pg:"created_at"`
 }
 
 // Metrics represents a collection of metrics for a web page defined in the
@@ -36,6 +59,10 @@ type metricStore interface {
 	Get(filter Filter) ([]Metric, error)
 }
 
+type certificateService interface {
+	Save(name string, cert scrape.Certificate) error
+}
+
 type metricService interface {
 	Get(f Filter) (Metrics, error)
 	Consume(name string, resCh <-chan scrape.Result)
@@ -43,12 +70,18 @@ type metricService interface {
 
 // Service provides methods to work with Metrics.
 type Service struct {
-	store metricStore
+	store              metricStore
+	certificateService certificateService
 }
 
 // NewService creates a new metric service.
-func NewService(store metricStore) *Service {
-	return &Service{store: store}
+func NewService(
+	store metricStore, certificateService certificateService,
+) *Service {
+	return &Service{
+		store:              store,
+		certificateService: certificateService,
+	}
 }
 
 // Get returns metrics that satisfy given filter, or empty Metrics if no
@@ -62,6 +95,7 @@ func (s *Service) Get(f Filter) (Metrics, error) {
 }
 
 // Consume runs infinite loop to consume all the results from the given channel.
+// Certificates of secure pages are saved with the certificate service.
 // Consume exits on result channel close.
 func (s *Service) Consume(name string, resCh <-chan scrape.Result) {
 	// iterate through the resCh.
@@ -69,15 +103,39 @@ func (s *Service) Consume(name string, resCh <-chan scrape.Result) {
 		// on each result: assemble Metric
 		m := Metric{
 			Name:              name,
+			Outcome:           string(r.Outcome),
+			ErrorKind:         string(r.ErrorKind),
+			ErrorMessage:      r.ErrorMessage,
 			StatusCode:        r.StatusCode,
 			ResponseSizeBytes: r.ResponseSizeBytes,
 			ResponseTimeMs:    r.ResponseTimeMs,
+			DNSTimeMs:         r.DNSTimeMs,
+			ConnectTimeMs:     r.ConnectTimeMs,
+			TLSTimeMs:         r.TLSTimeMs,
+			TTFBMs:            r.TTFBMs,
+			TransferTimeMs:    r.TransferTimeMs,
+			ConnReused:        r.ConnReused,
 			CreatedAt:         r.CreatedAt,
+			Redirects:         r.Redirects,
+			Assertions:        r.Assertions,
+			Script:            r.Script,
+			Attempts:          r.Attempts,
+			Throttled:         r.Throttled,
+			RetryAfterMs:      r.RetryAfterMs,
+			Details:           r.Details,
 		}
 		// store it in DB
 		_, err := s.store.Create(m)
 		if err != nil {
 			log.Printf("failed to store metric %#v %+v", m, err)
 		}
+
+		// store the certificate of a secure page
+		if r.Certificate != nil {
+			err = s.certificateService.Save(name, *r.Certificate)
+			if err != nil {
+				log.Printf("failed to store certificate %s %+v", name, err)
+			}
+		}
 	}
 }
@@ -50,12 +50,16 @@ func TestMetricService_Consume(t *testing.T) {
 		StatusCode:        testMetrics[0].StatusCode,
 		ResponseSizeBytes: testMetrics[0].ResponseSizeBytes,
 		ResponseTimeMs:    testMetrics[0].ResponseTimeMs,
+		TTFBMs:            testMetrics[0].TTFBMs,
+		ConnReused:        testMetrics[0].ConnReused,
 		CreatedAt:         testMetrics[0].CreatedAt,
 	}
 	r2 := scrape.Result{
 		StatusCode:        testMetrics[1].StatusCode,
 		ResponseSizeBytes: testMetrics[1].ResponseSizeBytes,
 		ResponseTimeMs:    testMetrics[1].ResponseTimeMs,
+		TTFBMs:            testMetrics[1].TTFBMs,
+		ConnReused:        testMetrics[1].ConnReused,
 		CreatedAt:         testMetrics[1].CreatedAt,
 	}
 	ch <- r1
@@ -71,8 +75,61 @@ func TestMetricService_Consume(t *testing.T) {
 	db.AssertExpectations(t)
 }
 
+func TestMetricService_Consume_FailedScrape(t *testing.T) {
+	ch := make(chan scrape.Result, 1)
+	createdAt := time.Now()
+	ch <- scrape.Result{
+		Outcome:      scrape.OutcomeFailure,
+		ErrorKind:    scrape.ErrorKindDNS,
+		ErrorMessage: "no such host",
+		CreatedAt:    createdAt,
+	}
+	close(ch)
+
+	svc, db := createTestService()
+	db.On("Create", Metric{
+		Name:         "test_metric_0",
+		Outcome:      "failure",
+		ErrorKind:    "dns",
+		ErrorMessage: "no such host",
+		CreatedAt:    createdAt,
+	}).
+		Return(Metric{}, nil).
+		Once()
+
+	svc.Consume("test_metric_0", ch)
+	db.AssertExpectations(t)
+}
+
+func TestMetricService_Consume_Certificate(t *testing.T) {
+	ch := make(chan scrape.Result, 2)
+	cert := scrape.Certificate{
+		Subject:  "CN=example.com",
+		Issuer:   "CN=Test CA",
+		SANs:     []string{"example.com"},
+		NotAfter: time.Now().Add(time.Hour),
+	}
+	ch <- scrape.Result{Outcome: scrape.OutcomeSuccess, Certificate: &cert}
+	ch <- scrape.Result{Outcome: scrape.OutcomeSuccess}
+	close(ch)
+
+	db := mockMetricStore{}
+	db.On("Create", mock.Anything).
+		Return(Metric{}, nil).
+		Twice()
+	certService := mockCertificateService{}
+	certService.On("Save", "test_metric_0", cert).
+		Return(assert.AnError).
+		Once()
+	svc := NewService(&db, &certService)
+
+	svc.Consume("test_metric_0", ch)
+	db.AssertExpectations(t)
+	certService.AssertExpectations(t)
+}
+
 func createTestService() (*Service, *mockMetricStore) {
 	db := mockMetricStore{}
-	svc := NewService(&db)
+	svc := NewService(&db, &mockCertificateService{})
 	return svc, &db
 }
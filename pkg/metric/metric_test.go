@@ -16,17 +16,22 @@ var testMetrics = []Metric{
 	{
 		ID:                0,
 		Name:              "test_metric_0",
+		Outcome:           "success",
 		StatusCode:        200,
 		ResponseSizeBytes: 201,
 		ResponseTimeMs:    202,
+		TTFBMs:            150,
 		CreatedAt:         time.Now(),
 	},
 	{
 		ID:                1,
 		Name:              "test_metric_0",
+		Outcome:           "success",
 		StatusCode:        400,
 		ResponseSizeBytes: 42,
 		ResponseTimeMs:    50,
+		TTFBMs:            40,
+		ConnReused:        true,
 		CreatedAt:         time.Now(),
 	},
 }
@@ -1,6 +1,10 @@
 package metric
 
 import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
 	"testing"
 	"time"
 
@@ -8,6 +12,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	"github.com/mneverov/webapp101/pkg/scrape"
 	"github.com/mneverov/webapp101/pkg/testutil"
 )
 
@@ -41,6 +46,7 @@ func TestMetricDB_Get(t *testing.T) {
 		assert.Len(t, res, 3)
 		for _, m := range res {
 			assert.Equal(t, f.Name, m.Name)
+			assert.Equal(t, "success", m.Outcome)
 			assert.True(t, !m.CreatedAt.Before(expectedOldestTimestamp))
 		}
 	})
@@ -60,10 +66,115 @@ func TestMetricDB_Create(t *testing.T) {
 	t.Run("should return successfully created metric", func(t *testing.T) {
 		m := Metric{
 			Name:              "example",
+			Outcome:           "success",
 			StatusCode:        201,
 			ResponseSizeBytes: 5,
 			ResponseTimeMs:    20,
-			CreatedAt:         time.Now().Truncate(time.Millisecond),
+			DNSTimeMs:         1,
+			ConnectTimeMs:     2,
+			TLSTimeMs:         3,
+			TTFBMs:            10,
+			TransferTimeMs:    4,
+			Redirects: []scrape.Redirect{{
+				URL:        "http://example.com/",
+				StatusCode: 301,
+				Location:   "https://example.com/",
+				LatencyMs:  5,
+			}},
+			CreatedAt: time.Now().Truncate(time.Millisecond),
+		}
+		res, err := db.Create(m)
+		assert.NoError(t, err)
+		m.ID = res.ID
+		m.CreatedAt = res.CreatedAt
+		assert.Equal(t, m, res)
+	})
+
+	t.Run("should return successfully created failed metric", func(t *testing.T) {
+		m := Metric{
+			Name:         "example",
+			Outcome:      "failure",
+			ErrorKind:    "assertion",
+			ErrorMessage: "assertions failed: contains: body does not match",
+			Assertions: []scrape.AssertionResult{{
+				Assertion: scrape.Assertion{
+					Type:  scrape.AssertionContains,
+					Value: "ok",
+				},
+				Message: "body does not match",
+			}},
+			CreatedAt: time.Now().Truncate(time.Millisecond),
+		}
+		res, err := db.Create(m)
+		assert.NoError(t, err)
+		m.ID = res.ID
+		m.CreatedAt = res.CreatedAt
+		assert.Equal(t, m, res)
+	})
+
+	t.Run("should return successfully created metric with details", func(t *testing.T) {
+		m := Metric{
+			Name:           "redis",
+			Outcome:        "success",
+			ResponseTimeMs: 3,
+			ConnectTimeMs:  1,
+			Details: &scrape.Details{TCP: &scrape.TCPDetails{
+				RemoteAddr: "127.0.0.1:6379",
+				Response:   "+PONG\r\n",
+			}},
+			CreatedAt: time.Now().Truncate(time.Millisecond),
+		}
+		res, err := db.Create(m)
+		assert.NoError(t, err)
+		m.ID = res.ID
+		m.CreatedAt = res.CreatedAt
+		assert.Equal(t, m, res)
+	})
+
+	t.Run("should store binary tcp response", func(t *testing.T) {
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		defer l.Close()
+		go func() {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+			// the beginning of a MySQL handshake.
+			_, _ = conn.Write([]byte("J\x00\x00\x00\n8.0.23\x00\xff\r\n"))
+		}()
+		s, err := scrape.NewDefaultRegistry(http.DefaultClient).New(scrape.Target{
+			Kind:     scrape.KindTCP,
+			URL:      l.Addr().String(),
+			Interval: time.Minute,
+			Settings: json.RawMessage(`{"expect":"\r\n"}`),
+		})
+		require.NoError(t, err)
+		r, err := s.Scrape(context.Background())
+		require.NoError(t, err)
+
+		m := Metric{
+			Name:           "redis",
+			Outcome:        string(r.Outcome),
+			ResponseTimeMs: r.ResponseTimeMs,
+			Details:        r.Details,
+			CreatedAt:      time.Now().Truncate(time.Millisecond),
+		}
+		res, err := db.Create(m)
+		assert.NoError(t, err)
+		assert.Equal(t, r.Details, res.Details)
+	})
+
+	t.Run("should return successfully created throttled metric", func(t *testing.T) {
+		m := Metric{
+			Name:           "example",
+			Outcome:        "success",
+			StatusCode:     429,
+			ResponseTimeMs: 12,
+			Throttled:      true,
+			RetryAfterMs:   60000,
+			CreatedAt:      time.Now().Truncate(time.Millisecond),
 		}
 		res, err := db.Create(m)
 		assert.NoError(t, err)
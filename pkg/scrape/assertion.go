@@ -0,0 +1,287 @@
+package scrape
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// AssertionType defines how an assertion is evaluated against a response.
+type AssertionType string
+
+// Supported assertion types.
+const (
+	// AssertionContains checks that the body contains the value.
+	AssertionContains AssertionType = "contains"
+	// AssertionNotContains checks that the body does not contain the value.
+	AssertionNotContains AssertionType = "not_contains"
+	// AssertionRegex checks that the body matches the value regex.
+	AssertionRegex AssertionType = "regex"
+	// AssertionJSONPathEquals checks that the JSON body has the value at the
+	// path.
+	AssertionJSONPathEquals AssertionType = "json_path_equals"
+	// AssertionJSONPathExists checks that the JSON body has the path.
+	AssertionJSONPathExists AssertionType = "json_path_exists"
+	// AssertionHeaderEquals checks that the header has the value.
+	AssertionHeaderEquals AssertionType = "header_equals"
+)
+
+// Assertion defines a check evaluated against every scraped response. Body
+// assertions see the first 1 MB of the body, not_contains fails when the body
+// is longer.
+type Assertion struct {
+	Type   AssertionType `json:"type"`
+	Value  string        `json:"value,omitempty"`
+	Path   string        `json:"path,omitempty"`
+	Header string        `json:"header,omitempty"`
+	// re is the compiled regex of a regex assertion.
+	re *regexp.Regexp
+}
+
+// AssertionResult represents an outcome of an assertion for a single scrape.
+type AssertionResult struct {
+	Assertion
+	Passed  bool   `json:"passed"`
+	Message string `json:"message,omitempty"`
+}
+
+// Validate checks that the assertion is well-formed.
+func (a Assertion) Validate() error {
+	switch a.Type {
+	case AssertionContains, AssertionNotContains:
+		if a.Value == "" {
+			return errors.Errorf("assertion %s requires a value", a.Type)
+		}
+	case AssertionRegex:
+		if _, err := regexp.Compile(a.Value); err != nil {
+			return errors.Wrapf(err, "assertion %s has invalid regex", a.Type)
+		}
+	case AssertionJSONPathEquals, AssertionJSONPathExists:
+		if _, err := parseJSONPath(a.Path); err != nil {
+			return errors.Wrapf(err, "assertion %s has invalid path", a.Type)
+		}
+	case AssertionHeaderEquals:
+		if a.Header == "" {
+			return errors.Errorf("assertion %s requires a header", a.Type)
+		}
+	default:
+		return errors.Errorf("unknown assertion type %q", a.Type)
+	}
+	return nil
+}
+
+// compileAssertions returns a copy of the valid assertions with their
+// regexes compiled, so that they are not compiled on every scrape.
+func compileAssertions(assertions []Assertion) []Assertion {
+	if len(assertions) == 0 {
+		return assertions
+	}
+	compiled := make([]Assertion, len(assertions))
+	for i, a := range assertions {
+		if a.Type == AssertionRegex {
+			a.re = regexp.MustCompile(a.Value)
+		}
+		compiled[i] = a
+	}
+	return compiled
+}
+
+// regexp returns the compiled regex of the assertion.
+func (a Assertion) regexp() *regexp.Regexp {
+	if a.re != nil {
+		return a.re
+	}
+	return regexp.MustCompile(a.Value)
+}
+
+// response contains parts of a response the assertions are evaluated
+// against. The body is truncated if it is longer than the asserted part.
+type response struct {
+	header    http.Header
+	body      []byte
+	truncated bool
+	// doc is the lazily decoded JSON body.
+	doc     interface{}
+	docErr  error
+	decoded bool
+}
+
+// json returns the decoded JSON body.
+func (r *response) json() (interface{}, error) {
+	if !r.decoded {
+		d := json.NewDecoder(bytes.NewReader(r.body))
+		d.UseNumber()
+		r.docErr = d.Decode(&r.doc)
+		r.decoded = true
+	}
+	return r.doc, r.docErr
+}
+
+// evaluate evaluates the assertion against the given response. The assertion
+// is expected to be valid.
+func (a Assertion) evaluate(resp *response) AssertionResult {
+	res := AssertionResult{Assertion: a}
+	res.re = nil
+	switch a.Type {
+	case AssertionContains:
+		res.Passed = bytes.Contains(resp.body, []byte(a.Value))
+	case AssertionNotContains:
+		res.Passed = !bytes.Contains(resp.body, []byte(a.Value))
+		if res.Passed && resp.truncated {
+			// the value may be in the part of the body that is not kept.
+			res.Passed = false
+			res.Message = fmt.Sprintf(
+				"body is longer than %d bytes", maxAssertedBodySize,
+			)
+			return res
+		}
+	case AssertionRegex:
+		res.Passed = a.regexp().Match(resp.body)
+	case AssertionJSONPathEquals, AssertionJSONPathExists:
+		value, err := resp.lookup(a.Path)
+		if err != nil {
+			res.Message = err.Error()
+			return res
+		}
+		res.Passed = a.Type == AssertionJSONPathExists || value == a.Value
+		if !res.Passed {
+			res.Message = fmt.Sprintf("got %q", value)
+		}
+		return res
+	case AssertionHeaderEquals:
+		value := resp.header.Get(a.Header)
+		res.Passed = value == a.Value
+		if !res.Passed {
+			res.Message = fmt.Sprintf("got %q", value)
+		}
+		return res
+	}
+
+	switch {
+	case res.Passed:
+	case resp.truncated:
+		res.Message = fmt.Sprintf(
+			"first %d bytes of body do not match", maxAssertedBodySize,
+		)
+	default:
+		res.Message = "body does not match"
+	}
+	return res
+}
+
+// lookup returns the string representation of the value at the given JSON
+// path of the body. The path is expected to be valid.
+func (r *response) lookup(jsonPath string) (string, error) {
+	doc, err := r.json()
+	if err != nil {
+		return "", errors.Wrap(err, "failed to decode body")
+	}
+
+	path, _ := parseJSONPath(jsonPath)
+	value, ok := path.lookup(doc)
+	if !ok {
+		return "", errors.Errorf("path %s not found", jsonPath)
+	}
+
+	switch v := value.(type) {
+	case string:
+		return v, nil
+	case json.Number:
+		return v.String(), nil
+	default:
+		b, _ := json.Marshal(v)
+		return string(b), nil
+	}
+}
+
+// evaluateAssertions evaluates all the assertions and returns the results and
+// an error describing failed assertions, if any.
+func evaluateAssertions(
+	assertions []Assertion, resp *response,
+) ([]AssertionResult, error) {
+	results := make([]AssertionResult, 0, len(assertions))
+	var failed []string
+	for _, a := range assertions {
+		res := a.evaluate(resp)
+		results = append(results, res)
+		if !res.Passed {
+			failed = append(failed, fmt.Sprintf("%s: %s", a.Type, res.Message))
+		}
+	}
+
+	if len(failed) > 0 {
+		return results, errors.Errorf(
+			"assertions failed: %s", strings.Join(failed, "; "),
+		)
+	}
+	return results, nil
+}
+
+// expectAssertions returns the assertions of the expected value and the
+// expected regex of a probe response, skipping empty ones.
+func expectAssertions(expect, expectRegex string) []Assertion {
+	var assertions []Assertion
+	if expect != "" {
+		assertions = append(assertions,
+			Assertion{Type: AssertionContains, Value: expect},
+		)
+	}
+	if expectRegex != "" {
+		assertions = append(assertions,
+			Assertion{Type: AssertionRegex, Value: expectRegex},
+		)
+	}
+	return assertions
+}
+
+// validateBodyAssertions checks the assertions of a probe without response
+// headers.
+func validateBodyAssertions(assertions []Assertion) error {
+	for _, a := range assertions {
+		if a.Type == AssertionHeaderEquals {
+			return errors.Errorf("assertion %s is not supported", a.Type)
+		}
+		if err := a.Validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// limitedBuffer keeps up to limit bytes written to it and discards the rest.
+type limitedBuffer struct {
+	buf       bytes.Buffer
+	limit     int
+	truncated bool
+}
+
+// Write writes the part of p that fits into the buffer. It always reports
+// the whole p as written.
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	room := b.limit - b.buf.Len()
+	if room < 0 {
+		room = 0
+	}
+	kept := p
+	if len(kept) > room {
+		b.truncated = true
+		kept = kept[:room]
+	}
+	b.buf.Write(kept)
+	return len(p), nil
+}
+
+// Bytes returns the kept bytes.
+func (b *limitedBuffer) Bytes() []byte {
+	return b.buf.Bytes()
+}
+
+// Truncated reports whether any written bytes were discarded.
+func (b *limitedBuffer) Truncated() bool {
+	return b.truncated
+}
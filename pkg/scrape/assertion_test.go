@@ -0,0 +1,242 @@
+package scrape
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testJSONBody = `{"status":"ok","items":[{"id":42,"stale":false}],"count":1}`
+
+func TestAssertion_Validate(t *testing.T) {
+	tests := []struct {
+		name      string
+		assertion Assertion
+		err       string
+	}{
+		{
+			name:      "unknown type",
+			assertion: Assertion{Type: "unknown"},
+			err:       "unknown assertion type",
+		},
+		{
+			name:      "contains without value",
+			assertion: Assertion{Type: AssertionContains},
+			err:       "requires a value",
+		},
+		{
+			name:      "invalid regex",
+			assertion: Assertion{Type: AssertionRegex, Value: "(unclosed"},
+			err:       "invalid regex",
+		},
+		{
+			name:      "invalid json path",
+			assertion: Assertion{Type: AssertionJSONPathExists, Path: "items"},
+			err:       "invalid path",
+		},
+		{
+			name:      "header without name",
+			assertion: Assertion{Type: AssertionHeaderEquals, Value: "ok"},
+			err:       "requires a header",
+		},
+		{
+			name:      "valid",
+			assertion: Assertion{Type: AssertionJSONPathEquals, Path: "$.status", Value: "ok"},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.assertion.Validate()
+			if tt.err == "" {
+				assert.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			assert.Regexp(t, tt.err, err)
+		})
+	}
+}
+
+func TestAssertion_Evaluate(t *testing.T) {
+	resp := &response{
+		header: http.Header{"Content-Type": []string{"application/json"}},
+		body:   []byte(testJSONBody),
+	}
+
+	tests := []struct {
+		name      string
+		assertion Assertion
+		passed    bool
+	}{
+		{
+			name:      "contains",
+			assertion: Assertion{Type: AssertionContains, Value: `"status":"ok"`},
+			passed:    true,
+		},
+		{
+			name:      "contains fails",
+			assertion: Assertion{Type: AssertionContains, Value: "error"},
+		},
+		{
+			name:      "not contains",
+			assertion: Assertion{Type: AssertionNotContains, Value: "error"},
+			passed:    true,
+		},
+		{
+			name:      "not contains fails",
+			assertion: Assertion{Type: AssertionNotContains, Value: "stale"},
+		},
+		{
+			name:      "regex",
+			assertion: Assertion{Type: AssertionRegex, Value: `"count":\d+`},
+			passed:    true,
+		},
+		{
+			name:      "json path equals string",
+			assertion: Assertion{Type: AssertionJSONPathEquals, Path: "$.status", Value: "ok"},
+			passed:    true,
+		},
+		{
+			name:      "json path equals number",
+			assertion: Assertion{Type: AssertionJSONPathEquals, Path: "$.items[0].id", Value: "42"},
+			passed:    true,
+		},
+		{
+			name:      "json path equals bool",
+			assertion: Assertion{Type: AssertionJSONPathEquals, Path: "$['items'][0]['stale']", Value: "false"},
+			passed:    true,
+		},
+		{
+			name:      "json path equals fails",
+			assertion: Assertion{Type: AssertionJSONPathEquals, Path: "$.count", Value: "2"},
+		},
+		{
+			name:      "json path exists",
+			assertion: Assertion{Type: AssertionJSONPathExists, Path: "$.items[0]"},
+			passed:    true,
+		},
+		{
+			name:      "json path exists fails",
+			assertion: Assertion{Type: AssertionJSONPathExists, Path: "$.items[1]"},
+		},
+		{
+			name:      "header equals",
+			assertion: Assertion{Type: AssertionHeaderEquals, Header: "content-type", Value: "application/json"},
+			passed:    true,
+		},
+		{
+			name:      "header equals fails",
+			assertion: Assertion{Type: AssertionHeaderEquals, Header: "X-Missing", Value: "value"},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			res := tt.assertion.evaluate(resp)
+
+			assert.Equal(t, tt.passed, res.Passed)
+			assert.Equal(t, tt.assertion, res.Assertion)
+			if !tt.passed {
+				assert.NotEmpty(t, res.Message)
+			}
+		})
+	}
+
+	t.Run("json path on invalid json", func(t *testing.T) {
+		a := Assertion{Type: AssertionJSONPathExists, Path: "$.status"}
+
+		res := a.evaluate(&response{body: []byte("<html>")})
+
+		assert.False(t, res.Passed)
+		assert.Regexp(t, "failed to decode body", res.Message)
+	})
+
+	t.Run("not contains fails on truncated body", func(t *testing.T) {
+		a := Assertion{Type: AssertionNotContains, Value: "error"}
+
+		res := a.evaluate(&response{body: []byte("ok"), truncated: true})
+
+		assert.False(t, res.Passed)
+		assert.Regexp(t, "body is longer than", res.Message)
+	})
+
+	t.Run("compiled regex", func(t *testing.T) {
+		a := Assertion{Type: AssertionRegex, Value: `"count":\s*1`}
+		compiled := compileAssertions([]Assertion{a})
+		require.NotNil(t, compiled[0].re)
+
+		res := compiled[0].evaluate(resp)
+
+		assert.True(t, res.Passed)
+		assert.Equal(t, a, res.Assertion)
+	})
+}
+
+func TestHTTPScraper_ScrapeAssertions(t *testing.T) {
+	client := &clientMock{
+		doMock: func(req *http.Request) (*http.Response, error) {
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Header:     http.Header{},
+				Body:       ioutil.NopCloser(strings.NewReader(testJSONBody)),
+			}, nil
+		},
+	}
+
+	t.Run("should succeed when all assertions pass", func(t *testing.T) {
+		s := newHTTPScraper(client, Target{
+			URL: "https://example.com",
+			Assertions: []Assertion{
+				{Type: AssertionContains, Value: "ok"},
+				{Type: AssertionJSONPathEquals, Path: "$.count", Value: "1"},
+			},
+		})
+
+		res, err := s.Scrape(context.Background())
+
+		require.NoError(t, err)
+		assert.Equal(t, OutcomeSuccess, res.Outcome)
+		assert.Len(t, res.Assertions, 2)
+		assert.Equal(t, int64(len(testJSONBody)), res.ResponseSizeBytes)
+	})
+
+	t.Run("should fail when an assertion fails", func(t *testing.T) {
+		s := newHTTPScraper(client, Target{
+			URL: "https://example.com",
+			Assertions: []Assertion{
+				{Type: AssertionContains, Value: "ok"},
+				{Type: AssertionNotContains, Value: "stale"},
+			},
+		})
+
+		res, err := s.Scrape(context.Background())
+
+		require.NoError(t, err)
+		assert.Equal(t, OutcomeFailure, res.Outcome)
+		assert.Equal(t, ErrorKindAssertion, res.ErrorKind)
+		assert.Regexp(t, "not_contains", res.ErrorMessage)
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+		require.Len(t, res.Assertions, 2)
+		assert.True(t, res.Assertions[0].Passed)
+		assert.False(t, res.Assertions[1].Passed)
+	})
+}
+
+func TestLimitedBuffer(t *testing.T) {
+	b := &limitedBuffer{limit: 4}
+
+	n, err := b.Write([]byte("7 bytes"))
+
+	assert.NoError(t, err)
+	assert.Equal(t, 7, n)
+	assert.Equal(t, []byte("7 by"), b.Bytes())
+	assert.True(t, b.Truncated())
+}
@@ -0,0 +1,117 @@
+package scrape
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/pkg/errors"
+)
+
+// AuthType defines how a request is authenticated.
+type AuthType string
+
+// Supported auth types.
+const (
+	// AuthBasic authenticates with a username and a password.
+	AuthBasic AuthType = "basic"
+	// AuthBearer authenticates with a bearer token.
+	AuthBearer AuthType = "bearer"
+	// AuthHeader authenticates with a named header, e.g. an API key.
+	AuthHeader AuthType = "header"
+)
+
+// Auth defines credentials applied to every request of a scraper.
+// Auth is never printed with its secrets: the password, the token and the
+// header value.
+type Auth struct {
+	Type     AuthType `json:"type"`
+	Username string   `json:"username,omitempty"`
+	Password string   `json:"password,omitempty"`
+	Token    string   `json:"token,omitempty"`
+	Header   string   `json:"header,omitempty"`
+	Value    string   `json:"value,omitempty"`
+}
+
+// Validate checks that the auth has all the credentials required by its
+// type.
+func (a Auth) Validate() error {
+	switch a.Type {
+	case AuthBasic:
+		if a.Username == "" {
+			return errors.Errorf("auth %s requires a username", a.Type)
+		}
+	case AuthBearer:
+		if a.Token == "" {
+			return errors.Errorf("auth %s requires a token", a.Type)
+		}
+	case AuthHeader:
+		if !headerNameRe.MatchString(a.Header) {
+			return errors.Errorf("auth %s has invalid header %q", a.Type, a.Header)
+		}
+		if a.Value == "" {
+			return errors.Errorf("auth %s requires a value", a.Type)
+		}
+	default:
+		return errors.Errorf("unknown auth type %q", a.Type)
+	}
+	return nil
+}
+
+// Redacted returns a copy of the auth without the secrets.
+func (a Auth) Redacted() Auth {
+	a.Password = ""
+	a.Token = ""
+	a.Value = ""
+	return a
+}
+
+// secretHeaders contains the canonical names of request headers that carry
+// credentials.
+var secretHeaders = map[string]bool{
+	"Authorization":       true,
+	"Proxy-Authorization": true,
+	"Cookie":              true,
+	"X-Api-Key":           true,
+	"X-Auth-Token":        true,
+}
+
+// RedactHeaders returns a copy of the request headers without the values of
+// the headers that carry credentials.
+func RedactHeaders(headers map[string]string) map[string]string {
+	if headers == nil {
+		return nil
+	}
+	redacted := make(map[string]string, len(headers))
+	for name, value := range headers {
+		if secretHeaders[http.CanonicalHeaderKey(name)] {
+			value = ""
+		}
+		redacted[name] = value
+	}
+	return redacted
+}
+
+// String returns the redacted auth.
+func (a Auth) String() string {
+	r := a.Redacted()
+	return fmt.Sprintf(
+		"{Type:%s Username:%s Header:%s}", r.Type, r.Username, r.Header,
+	)
+}
+
+// GoString returns the redacted auth.
+func (a Auth) GoString() string {
+	return "scrape.Auth" + a.String()
+}
+
+// apply sets the credentials on the given request.
+func (a Auth) apply(req *http.Request) {
+	switch a.Type {
+	case AuthBasic:
+		req.SetBasicAuth(a.Username, a.Password)
+	case AuthBearer:
+		req.Header.Set("Authorization", "Bearer "+a.Token)
+	case AuthHeader:
+		req.Header.Set(a.Header, a.Value)
+	}
+}
@@ -0,0 +1,136 @@
+package scrape
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuth_Validate(t *testing.T) {
+	tests := []struct {
+		name string
+		auth Auth
+		err  string
+	}{
+		{name: "unknown type", auth: Auth{Type: "digest"}, err: "unknown auth type"},
+		{name: "basic without username", auth: Auth{Type: AuthBasic}, err: "requires a username"},
+		{name: "bearer without token", auth: Auth{Type: AuthBearer}, err: "requires a token"},
+		{name: "header without name", auth: Auth{Type: AuthHeader, Value: "key"}, err: "invalid header"},
+		{name: "header without value", auth: Auth{Type: AuthHeader, Header: "X-Api-Key"}, err: "requires a value"},
+		{name: "valid basic", auth: Auth{Type: AuthBasic, Username: "user"}},
+		{name: "valid bearer", auth: Auth{Type: AuthBearer, Token: "token"}},
+		{name: "valid header", auth: Auth{Type: AuthHeader, Header: "X-Api-Key", Value: "key"}},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.auth.Validate()
+			if tt.err == "" {
+				assert.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			assert.Regexp(t, tt.err, err)
+		})
+	}
+}
+
+func TestAuth_Redacted(t *testing.T) {
+	auth := Auth{
+		Type:     AuthBasic,
+		Username: "user",
+		Password: "secret_password",
+		Token:    "secret_token",
+		Header:   "X-Api-Key",
+		Value:    "secret_value",
+	}
+
+	assert.Equal(t,
+		Auth{Type: AuthBasic, Username: "user", Header: "X-Api-Key"},
+		auth.Redacted(),
+	)
+
+	for _, format := range []string{"%v", "%+v", "%#v", "%s"} {
+		for _, v := range []interface{}{auth, &auth, Target{Auth: &auth}} {
+			assert.NotRegexp(t, "secret", fmt.Sprintf(format, v))
+		}
+	}
+}
+
+func TestRedactHeaders(t *testing.T) {
+	headers := map[string]string{
+		"Accept":        "application/json",
+		"authorization": "Bearer secret",
+		"Cookie":        "session=secret",
+		"X-API-KEY":     "secret",
+	}
+
+	assert.Equal(t,
+		map[string]string{
+			"Accept":        "application/json",
+			"authorization": "",
+			"Cookie":        "",
+			"X-API-KEY":     "",
+		},
+		RedactHeaders(headers),
+	)
+	assert.Equal(t, "Bearer secret", headers["authorization"])
+	assert.Nil(t, RedactHeaders(nil))
+}
+
+func TestHTTPScraper_ScrapeAuth(t *testing.T) {
+	tests := []struct {
+		name   string
+		auth   Auth
+		header string
+		value  string
+	}{
+		{
+			name:   "basic",
+			auth:   Auth{Type: AuthBasic, Username: "user", Password: "pass"},
+			header: "Authorization",
+			value:  "Basic dXNlcjpwYXNz",
+		},
+		{
+			name:   "bearer",
+			auth:   Auth{Type: AuthBearer, Token: "token"},
+			header: "Authorization",
+			value:  "Bearer token",
+		},
+		{
+			name:   "header",
+			auth:   Auth{Type: AuthHeader, Header: "X-Api-Key", Value: "key"},
+			header: "X-Api-Key",
+			value:  "key",
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			var received http.Header
+			server := httptest.NewServer(http.HandlerFunc(
+				func(w http.ResponseWriter, r *http.Request) {
+					received = r.Header
+				},
+			))
+			defer server.Close()
+
+			s := newHTTPScraper(server.Client(), Target{
+				URL:     server.URL,
+				Headers: map[string]string{tt.header: "overridden"},
+				Auth:    &tt.auth,
+			})
+			_, err := s.Scrape(context.Background())
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.value, received.Get(tt.header))
+		})
+	}
+}
@@ -0,0 +1,36 @@
+package scrape
+
+import (
+	"crypto/tls"
+	"time"
+)
+
+// Certificate represents a leaf TLS certificate presented by a scraped server.
+type Certificate struct {
+	Subject  string
+	Issuer   string
+	SANs     []string
+	NotAfter time.Time
+}
+
+// leafCertificate returns the leaf certificate of the given connection, or nil
+// if the connection is not secure.
+func leafCertificate(state *tls.ConnectionState) *Certificate {
+	if state == nil || len(state.PeerCertificates) == 0 {
+		return nil
+	}
+
+	leaf := state.PeerCertificates[0]
+	sans := make([]string, 0, len(leaf.DNSNames)+len(leaf.IPAddresses))
+	sans = append(sans, leaf.DNSNames...)
+	for _, ip := range leaf.IPAddresses {
+		sans = append(sans, ip.String())
+	}
+
+	return &Certificate{
+		Subject:  leaf.Subject.String(),
+		Issuer:   leaf.Issuer.String(),
+		SANs:     sans,
+		NotAfter: leaf.NotAfter,
+	}
+}
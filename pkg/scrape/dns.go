@@ -0,0 +1,225 @@
+package scrape
+
+import (
+	"context"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/pkg/errors"
+)
+
+// KindDNS is the kind of DNSScraper.
+const KindDNS Kind = "dns"
+
+// resolvConfPath is the path of the resolver config the default resolver is
+// read from.
+var resolvConfPath = "/etc/resolv.conf"
+
+// dnsRecordTypes contains the supported record types.
+var dnsRecordTypes = map[string]uint16{
+	"A":     dns.TypeA,
+	"AAAA":  dns.TypeAAAA,
+	"CNAME": dns.TypeCNAME,
+	"MX":    dns.TypeMX,
+	"TXT":   dns.TypeTXT,
+}
+
+// DNSSettings defines the settings of a dns target. Resolver is the host:port
+// of the resolver, the first nameserver of /etc/resolv.conf if empty.
+// RecordType is one of A, AAAA, CNAME, MX and TXT, A if empty. Protocol is
+// udp or tcp, udp if empty. Expect contains the answers that must be
+// returned, MX answers have the "preference host" form. Timeout limits the
+// lookup, 10s if empty.
+type DNSSettings struct {
+	Resolver   string   `json:"resolver,omitempty"`
+	RecordType string   `json:"record_type,omitempty"`
+	Protocol   string   `json:"protocol,omitempty"`
+	Expect     []string `json:"expect,omitempty"`
+	Timeout    string   `json:"timeout,omitempty"`
+}
+
+// DNSDetails contains the details of a dns lookup: the resolver, the record
+// type, the response code and the returned records of the type.
+type DNSDetails struct {
+	Resolver   string   `json:"resolver"`
+	RecordType string   `json:"record_type"`
+	Rcode      string   `json:"rcode"`
+	Records    []string `json:"records"`
+}
+
+// DNSScraper represents a scraper that resolves the hostname given as the
+// target URL against a resolver and gathers the returned records.
+type DNSScraper struct {
+	client     *dns.Client
+	resolver   string
+	name       string
+	recordType string
+	assertions []Assertion
+}
+
+// dnsFactory creates DNSScrapers.
+func dnsFactory(target Target) (Scraper, error) {
+	if err := target.validateNonHTTP(); err != nil {
+		return nil, err
+	}
+	if _, ok := dns.IsDomainName(target.URL); !ok || target.URL == "" {
+		return nil, errors.Errorf("invalid hostname %q", target.URL)
+	}
+
+	var settings DNSSettings
+	if err := decodeSettings(target.Settings, &settings); err != nil {
+		return nil, err
+	}
+	timeout, err := parseTimeout(settings.Timeout)
+	if err != nil {
+		return nil, err
+	}
+
+	recordType := strings.ToUpper(settings.RecordType)
+	if recordType == "" {
+		recordType = "A"
+	}
+	if _, ok := dnsRecordTypes[recordType]; !ok {
+		return nil, errors.Errorf("unsupported record type %q", settings.RecordType)
+	}
+
+	switch settings.Protocol {
+	case "", "udp", "tcp":
+	default:
+		return nil, errors.Errorf("unsupported protocol %q", settings.Protocol)
+	}
+
+	resolver := settings.Resolver
+	if resolver == "" {
+		if resolver, err = defaultResolver(); err != nil {
+			return nil, err
+		}
+	} else if err = validateAddress(resolver); err != nil {
+		return nil, errors.Wrap(err, "invalid resolver")
+	}
+
+	// the records are evaluated one per line, expected answers must match
+	// a whole line.
+	assertions := make([]Assertion, 0, len(settings.Expect)+len(target.Assertions))
+	for _, answer := range settings.Expect {
+		assertions = append(assertions, Assertion{
+			Type:  AssertionRegex,
+			Value: "(?m)^" + regexp.QuoteMeta(answer) + "$",
+		})
+	}
+	assertions = append(assertions, target.Assertions...)
+	if err = validateBodyAssertions(assertions); err != nil {
+		return nil, err
+	}
+
+	return &DNSScraper{
+		client:     &dns.Client{Net: settings.Protocol, Timeout: timeout},
+		resolver:   resolver,
+		name:       dns.Fqdn(target.URL),
+		recordType: recordType,
+		assertions: compileAssertions(assertions),
+	}, nil
+}
+
+// Scrape resolves the hostname. A response code other than NOERROR fails the
+// scrape with the dns error kind.
+func (s *DNSScraper) Scrape(ctx context.Context) (Result, error) {
+	// 1. Query the resolver
+	msg := new(dns.Msg)
+	msg.SetQuestion(s.name, dnsRecordTypes[s.recordType])
+
+	resp, rtt, err := s.client.ExchangeContext(ctx, msg, s.resolver)
+	if err != nil {
+		kind := ErrorKindDNS
+		if isTimeout(err) {
+			kind = ErrorKindTimeout
+		}
+		return Result{}, newError(
+			kind,
+			errors.Wrapf(err, "failed to resolve %s with %s", s.name, s.resolver),
+		)
+	}
+
+	// 2. Collect the records of the queried type
+	records := make([]string, 0, len(resp.Answer))
+	for _, rr := range resp.Answer {
+		if record, ok := formatRecord(rr, dnsRecordTypes[s.recordType]); ok {
+			records = append(records, record)
+		}
+	}
+
+	// 3. Assemble the result
+	rcode := dns.RcodeToString[resp.Rcode]
+	m := Result{
+		Outcome:           OutcomeSuccess,
+		ResponseSizeBytes: int64(resp.Len()),
+		ResponseTimeMs:    int(rtt.Milliseconds()),
+		DNSTimeMs:         int(rtt.Milliseconds()),
+		Details: &Details{DNS: &DNSDetails{
+			Resolver:   s.resolver,
+			RecordType: s.recordType,
+			Rcode:      rcode,
+			Records:    records,
+		}},
+		CreatedAt: time.Now(),
+	}
+	if resp.Rcode != dns.RcodeSuccess {
+		m.Outcome = OutcomeFailure
+		m.ErrorKind = ErrorKindDNS
+		m.ErrorMessage = "resolver " + s.resolver + " returned " + rcode +
+			" for " + s.name
+		return m, nil
+	}
+
+	// 4. Evaluate the expected answers
+	if len(s.assertions) > 0 {
+		m.Assertions, err = evaluateAssertions(
+			s.assertions, &response{body: []byte(strings.Join(records, "\n"))},
+		)
+		if err != nil {
+			m.Outcome = OutcomeFailure
+			m.ErrorKind = ErrorKindAssertion
+			m.ErrorMessage = err.Error()
+		}
+	}
+
+	return m, nil
+}
+
+// formatRecord returns the value of the given record if it is of the given
+// type.
+func formatRecord(rr dns.RR, recordType uint16) (string, bool) {
+	if rr.Header().Rrtype != recordType {
+		return "", false
+	}
+	switch r := rr.(type) {
+	case *dns.A:
+		return r.A.String(), true
+	case *dns.AAAA:
+		return r.AAAA.String(), true
+	case *dns.CNAME:
+		return r.Target, true
+	case *dns.MX:
+		return strconv.Itoa(int(r.Preference)) + " " + r.Mx, true
+	case *dns.TXT:
+		return strings.Join(r.Txt, ""), true
+	}
+	return "", false
+}
+
+// defaultResolver returns the address of the first nameserver of the system
+// resolver config.
+func defaultResolver() (string, error) {
+	cfg, err := dns.ClientConfigFromFile(resolvConfPath)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to read default resolver")
+	}
+	if len(cfg.Servers) == 0 {
+		return "", errors.Errorf("no nameserver in %s", resolvConfPath)
+	}
+	return net.JoinHostPort(cfg.Servers[0], cfg.Port), nil
+}
@@ -0,0 +1,260 @@
+package scrape
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"os"
+	"testing"
+
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDNSFactory(t *testing.T) {
+	tests := []struct {
+		name     string
+		target   Target
+		settings string
+		wantErr  string
+	}{
+		{
+			name:    "should return error on invalid hostname",
+			target:  Target{URL: "example..com"},
+			wantErr: "invalid hostname",
+		},
+		{
+			name:     "should return error on unsupported record type",
+			target:   Target{URL: "example.com"},
+			settings: `{"record_type":"SRV","resolver":"127.0.0.1:53"}`,
+			wantErr:  "unsupported record type",
+		},
+		{
+			name:     "should return error on unsupported protocol",
+			target:   Target{URL: "example.com"},
+			settings: `{"protocol":"quic","resolver":"127.0.0.1:53"}`,
+			wantErr:  "unsupported protocol",
+		},
+		{
+			name:     "should return error on invalid resolver",
+			target:   Target{URL: "example.com"},
+			settings: `{"resolver":"127.0.0.1"}`,
+			wantErr:  "invalid resolver",
+		},
+		{
+			name: "should return error on http request fields",
+			target: Target{
+				URL: "example.com", Headers: map[string]string{"Accept": "*/*"},
+			},
+			wantErr: "not supported",
+		},
+		{
+			name:     "should create scraper",
+			target:   Target{URL: "example.com"},
+			settings: `{"record_type":"mx","resolver":"127.0.0.1:53","expect":["10 mx.example.com."]}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			target := tt.target
+			target.Kind = KindDNS
+			if tt.settings != "" {
+				target.Settings = json.RawMessage(tt.settings)
+			}
+
+			s, err := dnsFactory(target)
+
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Regexp(t, tt.wantErr, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.IsType(t, &DNSScraper{}, s)
+		})
+	}
+
+	t.Run("should use system resolver by default", func(t *testing.T) {
+		f, err := ioutil.TempFile("", "resolv.conf")
+		require.NoError(t, err)
+		defer os.Remove(f.Name())
+		_, err = f.WriteString("nameserver 192.0.2.53\n")
+		require.NoError(t, err)
+		require.NoError(t, f.Close())
+
+		defer func(path string) { resolvConfPath = path }(resolvConfPath)
+		resolvConfPath = f.Name()
+
+		s, err := dnsFactory(Target{Kind: KindDNS, URL: "example.com"})
+
+		require.NoError(t, err)
+		assert.Equal(t, "192.0.2.53:53", s.(*DNSScraper).resolver)
+	})
+}
+
+func TestDNSScraper_Scrape(t *testing.T) {
+	resolver := startDNSServer(t, map[string][]dns.RR{
+		"example.com.": {
+			mustRR(t, "example.com. 60 IN A 192.0.2.1"),
+			mustRR(t, "example.com. 60 IN A 192.0.2.2"),
+			mustRR(t, "example.com. 60 IN MX 10 mx.example.com."),
+			mustRR(t, `example.com. 60 IN TXT "v=spf1 " "-all"`),
+		},
+		"www.example.com.": {
+			mustRR(t, "www.example.com. 60 IN CNAME example.com."),
+			mustRR(t, "example.com. 60 IN A 192.0.2.1"),
+		},
+	})
+
+	tests := []struct {
+		name        string
+		host        string
+		settings    string
+		wantOutcome Outcome
+		wantKind    ErrorKind
+		wantRcode   string
+		wantRecords []string
+	}{
+		{
+			name:        "should return A records",
+			host:        "example.com",
+			wantOutcome: OutcomeSuccess,
+			wantRcode:   "NOERROR",
+			wantRecords: []string{"192.0.2.1", "192.0.2.2"},
+		},
+		{
+			name:        "should return MX records",
+			host:        "example.com",
+			settings:    `"record_type":"MX",`,
+			wantOutcome: OutcomeSuccess,
+			wantRcode:   "NOERROR",
+			wantRecords: []string{"10 mx.example.com."},
+		},
+		{
+			name:        "should return TXT records",
+			host:        "example.com",
+			settings:    `"record_type":"TXT",`,
+			wantOutcome: OutcomeSuccess,
+			wantRcode:   "NOERROR",
+			wantRecords: []string{"v=spf1 -all"},
+		},
+		{
+			name:        "should return only records of queried type",
+			host:        "www.example.com",
+			settings:    `"record_type":"CNAME",`,
+			wantOutcome: OutcomeSuccess,
+			wantRcode:   "NOERROR",
+			wantRecords: []string{"example.com."},
+		},
+		{
+			name:        "should pass when expected answers are returned",
+			host:        "example.com",
+			settings:    `"expect":["192.0.2.2","192.0.2.1"],`,
+			wantOutcome: OutcomeSuccess,
+			wantRcode:   "NOERROR",
+			wantRecords: []string{"192.0.2.1", "192.0.2.2"},
+		},
+		{
+			name:        "should fail when expected answer is missing",
+			host:        "example.com",
+			settings:    `"expect":["192.0.2.1","192.0.2.3"],`,
+			wantOutcome: OutcomeFailure,
+			wantKind:    ErrorKindAssertion,
+			wantRcode:   "NOERROR",
+			wantRecords: []string{"192.0.2.1", "192.0.2.2"},
+		},
+		{
+			name:        "should not match part of answer",
+			host:        "example.com",
+			settings:    `"expect":["192.0.2."],`,
+			wantOutcome: OutcomeFailure,
+			wantKind:    ErrorKindAssertion,
+			wantRcode:   "NOERROR",
+			wantRecords: []string{"192.0.2.1", "192.0.2.2"},
+		},
+		{
+			name:        "should fail on NXDOMAIN",
+			host:        "missing.example.com",
+			wantOutcome: OutcomeFailure,
+			wantKind:    ErrorKindDNS,
+			wantRcode:   "NXDOMAIN",
+			wantRecords: []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := dnsFactory(Target{
+				Kind:     KindDNS,
+				URL:      tt.host,
+				Settings: json.RawMessage(`{` + tt.settings + `"resolver":"` + resolver + `"}`),
+			})
+			require.NoError(t, err)
+
+			res, err := s.Scrape(context.Background())
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantOutcome, res.Outcome, res.ErrorMessage)
+			assert.Equal(t, tt.wantKind, res.ErrorKind)
+			require.NotNil(t, res.Details.DNS)
+			assert.Equal(t, resolver, res.Details.DNS.Resolver)
+			assert.Equal(t, tt.wantRcode, res.Details.DNS.Rcode)
+			assert.Equal(t, tt.wantRecords, res.Details.DNS.Records)
+		})
+	}
+
+	t.Run("should return timeout error when resolver does not answer", func(t *testing.T) {
+		conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+		require.NoError(t, err)
+		defer conn.Close()
+
+		s, err := dnsFactory(Target{
+			Kind:     KindDNS,
+			URL:      "example.com",
+			Settings: json.RawMessage(`{"resolver":"` + conn.LocalAddr().String() + `","timeout":"50ms"}`),
+		})
+		require.NoError(t, err)
+
+		_, err = s.Scrape(context.Background())
+
+		require.Error(t, err)
+		assert.Equal(t, ErrorKindTimeout, kindOf(err))
+	})
+}
+
+// startDNSServer starts a local stub resolver that answers with the given
+// records and NXDOMAIN for unknown names.
+func startDNSServer(t *testing.T, zone map[string][]dns.RR) string {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	started := make(chan struct{})
+	srv := &dns.Server{
+		PacketConn:        conn,
+		NotifyStartedFunc: func() { close(started) },
+		Handler: dns.HandlerFunc(func(w dns.ResponseWriter, req *dns.Msg) {
+			resp := new(dns.Msg)
+			resp.SetReply(req)
+			answer, ok := zone[req.Question[0].Name]
+			if !ok {
+				resp.Rcode = dns.RcodeNameError
+			}
+			resp.Answer = answer
+			_ = w.WriteMsg(resp)
+		}),
+	}
+	go func() { _ = srv.ActivateAndServe() }()
+	t.Cleanup(func() { _ = srv.Shutdown() })
+	<-started
+
+	return conn.LocalAddr().String()
+}
+
+func mustRR(t *testing.T, s string) dns.RR {
+	rr, err := dns.NewRR(s)
+	require.NoError(t, err)
+	return rr
+}
@@ -0,0 +1,108 @@
+package scrape
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"net"
+)
+
+// ErrorKind classifies the reason of a failed scrape.
+type ErrorKind string
+
+// Possible kinds of scrape errors.
+const (
+	ErrorKindDNS        ErrorKind = "dns"
+	ErrorKindConnect    ErrorKind = "connect"
+	ErrorKindTLS        ErrorKind = "tls"
+	ErrorKindTimeout    ErrorKind = "timeout"
+	ErrorKindRead       ErrorKind = "read"
+	ErrorKindInvalidURL ErrorKind = "invalid-url"
+	ErrorKindAssertion  ErrorKind = "assertion"
+	ErrorKindStatus     ErrorKind = "status"
+	ErrorKindScript     ErrorKind = "script"
+	ErrorKindUnknown    ErrorKind = "unknown"
+)
+
+// Error represents a scrape error of a particular kind.
+type Error struct {
+	Kind ErrorKind
+	Err  error
+}
+
+// newError returns a new scrape error of the given kind.
+func newError(kind ErrorKind, err error) *Error {
+	return &Error{Kind: kind, Err: err}
+}
+
+// Error returns the message of the underlying error.
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the underlying error.
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// kindOf returns the kind of the given scrape error, or ErrorKindUnknown if
+// the error is not classified.
+func kindOf(err error) ErrorKind {
+	var scrapeErr *Error
+	if errors.As(err, &scrapeErr) {
+		return scrapeErr.Kind
+	}
+	return ErrorKindUnknown
+}
+
+// classifyRequestError returns the kind of an error that occurred while
+// sending a request and receiving the response headers.
+func classifyRequestError(err error) ErrorKind {
+	var (
+		dnsErr       *net.DNSError
+		opErr        *net.OpError
+		recordErr    tls.RecordHeaderError
+		authorityErr x509.UnknownAuthorityError
+		hostnameErr  x509.HostnameError
+		invalidErr   x509.CertificateInvalidError
+	)
+
+	switch {
+	case errors.As(err, &dnsErr):
+		return ErrorKindDNS
+	case isTimeout(err):
+		return ErrorKindTimeout
+	case errors.As(err, &recordErr),
+		errors.As(err, &authorityErr),
+		errors.As(err, &hostnameErr),
+		errors.As(err, &invalidErr):
+		return ErrorKindTLS
+	case errors.As(err, &opErr):
+		switch opErr.Op {
+		case "dial":
+			return ErrorKindConnect
+		case "remote error":
+			// TLS alerts sent by the server are reported as remote errors.
+			return ErrorKindTLS
+		}
+	}
+	return ErrorKindUnknown
+}
+
+// classifyReadError returns the kind of an error that occurred while reading
+// the response body.
+func classifyReadError(err error) ErrorKind {
+	if isTimeout(err) {
+		return ErrorKindTimeout
+	}
+	return ErrorKindRead
+}
+
+func isTimeout(err error) bool {
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+	return errors.Is(err, context.DeadlineExceeded)
+}
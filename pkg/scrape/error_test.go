@@ -0,0 +1,106 @@
+package scrape
+
+import (
+	"context"
+	"crypto/x509"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClassifyRequestError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		kind ErrorKind
+	}{
+		{
+			name: "dns",
+			err:  &net.DNSError{Err: "no such host", Name: "example.invalid"},
+			kind: ErrorKindDNS,
+		},
+		{
+			name: "connect",
+			err:  &net.OpError{Op: "dial", Err: assert.AnError},
+			kind: ErrorKindConnect,
+		},
+		{
+			name: "tls",
+			err:  x509.UnknownAuthorityError{},
+			kind: ErrorKindTLS,
+		},
+		{
+			name: "tls alert",
+			err:  &net.OpError{Op: "remote error", Err: assert.AnError},
+			kind: ErrorKindTLS,
+		},
+		{
+			name: "timeout",
+			err:  context.DeadlineExceeded,
+			kind: ErrorKindTimeout,
+		},
+		{
+			name: "unknown",
+			err:  assert.AnError,
+			kind: ErrorKindUnknown,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			err := &url.Error{Op: "Get", URL: "https://example.com", Err: tt.err}
+			wrapped := errors.Wrap(err, "request failed")
+
+			assert.Equal(t, tt.kind, classifyRequestError(wrapped))
+		})
+	}
+}
+
+func TestHTTPScraper_ScrapeErrorKinds(t *testing.T) {
+	t.Run("should classify refused connection", func(t *testing.T) {
+		server := httptest.NewServer(http.NotFoundHandler())
+		server.Close()
+
+		s := newHTTPScraper(&http.Client{}, Target{URL: server.URL})
+		_, err := s.Scrape(context.Background())
+
+		require.Error(t, err)
+		assert.Equal(t, ErrorKindConnect, kindOf(err))
+	})
+
+	t.Run("should classify timeout", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {
+				time.Sleep(100 * time.Millisecond)
+			},
+		))
+		defer server.Close()
+
+		s := newHTTPScraper(
+			&http.Client{Timeout: time.Millisecond}, Target{URL: server.URL},
+		)
+		_, err := s.Scrape(context.Background())
+
+		require.Error(t, err)
+		assert.Equal(t, ErrorKindTimeout, kindOf(err))
+	})
+
+	t.Run("should classify untrusted certificate", func(t *testing.T) {
+		server := httptest.NewTLSServer(http.NotFoundHandler())
+		defer server.Close()
+
+		s := newHTTPScraper(&http.Client{}, Target{URL: server.URL})
+		_, err := s.Scrape(context.Background())
+
+		require.Error(t, err)
+		assert.Equal(t, ErrorKindTLS, kindOf(err))
+	})
+}
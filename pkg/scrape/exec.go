@@ -0,0 +1,181 @@
+package scrape
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// KindExec is the kind of ExecScraper.
+const KindExec Kind = "exec"
+
+// Check states of Nagios-style plugins.
+const (
+	CheckStateOK       = "OK"
+	CheckStateWarning  = "WARNING"
+	CheckStateCritical = "CRITICAL"
+	CheckStateUnknown  = "UNKNOWN"
+)
+
+// checkStates maps the exit codes of Nagios-style plugins to the states.
+var checkStates = map[int]string{
+	0: CheckStateOK,
+	1: CheckStateWarning,
+	2: CheckStateCritical,
+	3: CheckStateUnknown,
+}
+
+// ExecSettings defines the settings of an exec target. Args are passed to the
+// command. Timeout limits the command run, 10s if empty.
+type ExecSettings struct {
+	Args    []string `json:"args,omitempty"`
+	Timeout string   `json:"timeout,omitempty"`
+}
+
+// ExecDetails contains the details of a check run: the state, the exit code,
+// the beginning of the status text and the performance data.
+type ExecDetails struct {
+	State    string       `json:"state"`
+	ExitCode int          `json:"exit_code"`
+	Output   string       `json:"output,omitempty"`
+	PerfData []PerfSample `json:"perf_data,omitempty"`
+}
+
+// ExecScraper represents a scraper that runs a Nagios-style check command.
+// Exit codes 0, 1, 2 and 3 map to the OK, WARNING, CRITICAL and UNKNOWN
+// states, other codes to UNKNOWN.
+type ExecScraper struct {
+	path    string
+	args    []string
+	timeout time.Duration
+}
+
+// NewExecFactory returns a factory of ExecScrapers. The target URL is the
+// name of a command in the given checks directory, commands outside of it
+// cannot be run.
+func NewExecFactory(checksDir string) Factory {
+	return func(target Target) (Scraper, error) {
+		if err := target.validateNonHTTP(); err != nil {
+			return nil, err
+		}
+		if len(target.Assertions) > 0 {
+			return nil, errors.Errorf("assertions are not supported by %s probes", KindExec)
+		}
+
+		name := target.URL
+		if name == "" || name == "." || name == ".." ||
+			strings.ContainsAny(name, `/\`) {
+			return nil, errors.Errorf("invalid check command %q", name)
+		}
+		path := filepath.Join(checksDir, name)
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, errors.Wrapf(err, "check command %q not found", name)
+		}
+		if !info.Mode().IsRegular() || info.Mode().Perm()&0111 == 0 {
+			return nil, errors.Errorf("check command %q is not executable", name)
+		}
+
+		var settings ExecSettings
+		if err = decodeSettings(target.Settings, &settings); err != nil {
+			return nil, err
+		}
+		timeout, err := parseTimeout(settings.Timeout)
+		if err != nil {
+			return nil, err
+		}
+
+		return &ExecScraper{
+			path:    path,
+			args:    settings.Args,
+			timeout: timeout,
+		}, nil
+	}
+}
+
+// Scrape runs the check command and parses its output. The CRITICAL and
+// UNKNOWN states fail the scrape with the status error kind, the WARNING
+// state does not.
+func (s *ExecScraper) Scrape(ctx context.Context) (Result, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	// 1. Run the command. The output goes to a file rather than a pipe, so
+	// that children of a killed command do not block the wait.
+	out, err := ioutil.TempFile("", "check")
+	if err != nil {
+		return Result{}, errors.Wrap(err, "failed to create check output")
+	}
+	defer func() {
+		_ = out.Close()
+		if err := os.Remove(out.Name()); err != nil {
+			log.Printf("failed to remove check output, %s", err)
+		}
+	}()
+	cmd := exec.CommandContext(ctx, s.path, s.args...)
+	cmd.Stdout = out
+
+	start := time.Now()
+	err = cmd.Run()
+	end := time.Now()
+	if ctx.Err() == context.DeadlineExceeded {
+		return Result{}, newError(
+			ErrorKindTimeout,
+			errors.Errorf("check %s timed out after %s", s.path, s.timeout),
+		)
+	}
+	var exitErr *exec.ExitError
+	if err != nil && !errors.As(err, &exitErr) {
+		return Result{}, errors.Wrapf(err, "failed to run check %s", s.path)
+	}
+
+	stdout := &limitedBuffer{limit: maxAssertedBodySize}
+	var size int64
+	if _, err = out.Seek(0, io.SeekStart); err == nil {
+		size, err = io.Copy(stdout, out)
+	}
+	if err != nil {
+		return Result{}, errors.Wrapf(err, "failed to read output of check %s", s.path)
+	}
+
+	// 2. Map the exit code to the state and parse the output
+	exitCode := cmd.ProcessState.ExitCode()
+	state, ok := checkStates[exitCode]
+	if !ok {
+		state = CheckStateUnknown
+	}
+	text, perfData := parseCheckOutput(string(stdout.Bytes()))
+	output := text
+	if len(output) > maxDetailsResponseSize {
+		output = output[:maxDetailsResponseSize]
+	}
+
+	// 3. Assemble the result
+	m := Result{
+		Outcome:           OutcomeSuccess,
+		ResponseSizeBytes: size,
+		ResponseTimeMs:    int(end.Sub(start).Milliseconds()),
+		Details: &Details{Exec: &ExecDetails{
+			State:    state,
+			ExitCode: exitCode,
+			Output:   output,
+			PerfData: perfData,
+		}},
+		CreatedAt: time.Now(),
+	}
+	if state == CheckStateCritical || state == CheckStateUnknown {
+		m.Outcome = OutcomeFailure
+		m.ErrorKind = ErrorKindStatus
+		m.ErrorMessage = state + ": " + text
+	}
+
+	return m, nil
+}
@@ -0,0 +1,186 @@
+package scrape
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExecFactory(t *testing.T) {
+	dir := t.TempDir()
+	writeCheck(t, dir, "check_ok", "exit 0")
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "readme"), nil, 0600))
+	factory := NewExecFactory(dir)
+
+	tests := []struct {
+		name    string
+		target  Target
+		wantErr string
+	}{
+		{
+			name:    "should return error on path outside of checks dir",
+			target:  Target{URL: "../check_ok"},
+			wantErr: "invalid check command",
+		},
+		{
+			name:    "should return error on absolute path",
+			target:  Target{URL: "/bin/sh"},
+			wantErr: "invalid check command",
+		},
+		{
+			name:    "should return error on missing command",
+			target:  Target{URL: "check_missing"},
+			wantErr: "not found",
+		},
+		{
+			name:    "should return error on not executable command",
+			target:  Target{URL: "readme"},
+			wantErr: "not executable",
+		},
+		{
+			name: "should return error on invalid timeout",
+			target: Target{
+				URL: "check_ok", Settings: json.RawMessage(`{"timeout":"soon"}`),
+			},
+			wantErr: "timeout",
+		},
+		{
+			name: "should create scraper",
+			target: Target{
+				URL: "check_ok", Settings: json.RawMessage(`{"args":["-w","80"]}`),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			target := tt.target
+			target.Kind = KindExec
+
+			s, err := factory(target)
+
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Regexp(t, tt.wantErr, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.IsType(t, &ExecScraper{}, s)
+		})
+	}
+}
+
+func TestExecScraper_Scrape(t *testing.T) {
+	dir := t.TempDir()
+	writeCheck(t, dir, "check_args", `echo "OK - args $1 $2 | value=$2;1;2"`)
+	writeCheck(t, dir, "check_warning", `echo "WARNING - load high"; exit 1`)
+	writeCheck(t, dir, "check_critical", `echo "CRITICAL - disk full | /=99%"; exit 2`)
+	writeCheck(t, dir, "check_unknown", `echo "UNKNOWN - no data"; exit 3`)
+	writeCheck(t, dir, "check_other", `exit 42`)
+	writeCheck(t, dir, "check_slow", `sleep 5`)
+	factory := NewExecFactory(dir)
+
+	tests := []struct {
+		name         string
+		command      string
+		settings     string
+		wantOutcome  Outcome
+		wantState    string
+		wantExitCode int
+		wantOutput   string
+		wantPerfData []PerfSample
+	}{
+		{
+			name:         "should pass args and parse perfdata",
+			command:      "check_args",
+			settings:     `{"args":["-w","7"]}`,
+			wantOutcome:  OutcomeSuccess,
+			wantState:    CheckStateOK,
+			wantOutput:   "OK - args -w 7",
+			wantPerfData: []PerfSample{{Label: "value", Value: 7, Warn: "1", Crit: "2"}},
+		},
+		{
+			name:         "should succeed on warning",
+			command:      "check_warning",
+			wantOutcome:  OutcomeSuccess,
+			wantState:    CheckStateWarning,
+			wantExitCode: 1,
+			wantOutput:   "WARNING - load high",
+		},
+		{
+			name:         "should fail on critical",
+			command:      "check_critical",
+			wantOutcome:  OutcomeFailure,
+			wantState:    CheckStateCritical,
+			wantExitCode: 2,
+			wantOutput:   "CRITICAL - disk full",
+			wantPerfData: []PerfSample{{Label: "/", Value: 99, Unit: "%"}},
+		},
+		{
+			name:         "should fail on unknown",
+			command:      "check_unknown",
+			wantOutcome:  OutcomeFailure,
+			wantState:    CheckStateUnknown,
+			wantExitCode: 3,
+			wantOutput:   "UNKNOWN - no data",
+		},
+		{
+			name:         "should map other exit codes to unknown",
+			command:      "check_other",
+			wantOutcome:  OutcomeFailure,
+			wantState:    CheckStateUnknown,
+			wantExitCode: 42,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			target := Target{Kind: KindExec, URL: tt.command}
+			if tt.settings != "" {
+				target.Settings = json.RawMessage(tt.settings)
+			}
+			s, err := factory(target)
+			require.NoError(t, err)
+
+			res, err := s.Scrape(context.Background())
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantOutcome, res.Outcome)
+			if tt.wantOutcome == OutcomeFailure {
+				assert.Equal(t, ErrorKindStatus, res.ErrorKind)
+			}
+			require.NotNil(t, res.Details.Exec)
+			assert.Equal(t, tt.wantState, res.Details.Exec.State)
+			assert.Equal(t, tt.wantExitCode, res.Details.Exec.ExitCode)
+			assert.Equal(t, tt.wantOutput, res.Details.Exec.Output)
+			assert.Equal(t, tt.wantPerfData, res.Details.Exec.PerfData)
+		})
+	}
+
+	t.Run("should return timeout error", func(t *testing.T) {
+		s, err := factory(Target{
+			Kind:     KindExec,
+			URL:      "check_slow",
+			Settings: json.RawMessage(`{"timeout":"100ms"}`),
+		})
+		require.NoError(t, err)
+
+		_, err = s.Scrape(context.Background())
+
+		require.Error(t, err)
+		assert.Equal(t, ErrorKindTimeout, kindOf(err))
+	})
+}
+
+// writeCheck writes an executable shell script with the given body.
+func writeCheck(t *testing.T, dir, name, body string) {
+	err := ioutil.WriteFile(
+		filepath.Join(dir, name), []byte("#!/bin/sh\n"+body+"\n"), 0700,
+	)
+	require.NoError(t, err)
+}
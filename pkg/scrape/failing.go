@@ -0,0 +1,115 @@
+package scrape
+
+import (
+	"log"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// FailingPolicy defines the scraping interval of a failing target. The target
+// is scraped every Interval after Failures consecutive failed scrapes, 1 if
+// zero, and returns to the scraping interval after Successes consecutive
+// successful scrapes, 1 if zero. A scrape fails if it has the failure outcome
+// or, for http and transaction probes, a non-2xx status code.
+type FailingPolicy struct {
+	Interval  string `json:"interval"`
+	Failures  int    `json:"failures,omitempty"`
+	Successes int    `json:"successes,omitempty"`
+}
+
+// Validate checks that the policy is well-formed.
+func (p FailingPolicy) Validate() error {
+	if _, err := p.interval(); err != nil {
+		return err
+	}
+	if p.Failures < 0 {
+		return errors.Errorf("failing failures %d must not be negative", p.Failures)
+	}
+	if p.Successes < 0 {
+		return errors.Errorf("failing successes %d must not be negative", p.Successes)
+	}
+	return nil
+}
+
+// interval returns the scraping interval of a failing target.
+func (p FailingPolicy) interval() (time.Duration, error) {
+	d, err := time.ParseDuration(p.Interval)
+	if err != nil || d <= 0 {
+		return 0, errors.Errorf("invalid failing interval %q", p.Interval)
+	}
+	return d, nil
+}
+
+// failingTracker tracks whether a target is failing by the results of its
+// scrapes.
+type failingTracker struct {
+	kind      Kind
+	interval  time.Duration
+	failures  int
+	successes int
+	failing   bool
+	// streak is the number of consecutive results that contradict the
+	// current state.
+	streak int
+}
+
+// newFailingTracker returns a new failingTracker of a validated policy for a
+// target of the given kind.
+func newFailingTracker(kind Kind, policy FailingPolicy) *failingTracker {
+	t := &failingTracker{
+		kind:      kind,
+		failures:  policy.Failures,
+		successes: policy.Successes,
+	}
+	t.interval, _ = policy.interval()
+	if t.failures == 0 {
+		t.failures = 1
+	}
+	if t.successes == 0 {
+		t.successes = 1
+	}
+	return t
+}
+
+// observe records the given scrape result and reports whether the target is
+// failing. Throttled scrapes are ignored.
+func (t *failingTracker) observe(name string, res Result) bool {
+	if res.Throttled {
+		return t.failing
+	}
+
+	if failed(t.kind, res) != t.failing {
+		t.streak++
+	} else {
+		t.streak = 0
+	}
+
+	threshold := t.failures
+	if t.failing {
+		threshold = t.successes
+	}
+	if t.streak >= threshold {
+		t.failing = !t.failing
+		t.streak = 0
+		if t.failing {
+			log.Printf("scraper %s is failing, scrape every %s\n", name, t.interval)
+		} else {
+			log.Printf("scraper %s recovered\n", name)
+		}
+	}
+	return t.failing
+}
+
+// failed reports whether the scrape of a target of the given kind with the
+// given result failed. The status code is an http status only for http and
+// transaction probes, websocket probes report 101 for a healthy handshake.
+func failed(kind Kind, res Result) bool {
+	if res.Outcome == OutcomeFailure {
+		return true
+	}
+	if kind != KindHTTP && kind != KindTransaction {
+		return false
+	}
+	return res.StatusCode != 0 && (res.StatusCode < 200 || res.StatusCode > 299)
+}
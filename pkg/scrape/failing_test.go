@@ -0,0 +1,106 @@
+package scrape
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFailingPolicy_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  FailingPolicy
+		wantErr string
+	}{
+		{
+			name:    "should return error on missing interval",
+			policy:  FailingPolicy{},
+			wantErr: "invalid failing interval",
+		},
+		{
+			name:    "should return error on non-positive interval",
+			policy:  FailingPolicy{Interval: "0s"},
+			wantErr: "invalid failing interval",
+		},
+		{
+			name:    "should return error on negative failures",
+			policy:  FailingPolicy{Interval: "10s", Failures: -1},
+			wantErr: "must not be negative",
+		},
+		{
+			name:    "should return error on negative successes",
+			policy:  FailingPolicy{Interval: "10s", Successes: -1},
+			wantErr: "must not be negative",
+		},
+		{
+			name:   "should accept valid policy",
+			policy: FailingPolicy{Interval: "10s", Failures: 3, Successes: 2},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.policy.Validate()
+
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Regexp(t, tt.wantErr, err)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestFailingTracker_Observe(t *testing.T) {
+	ok := Result{Outcome: OutcomeSuccess, StatusCode: http.StatusOK}
+	failure := Result{Outcome: OutcomeFailure, ErrorKind: ErrorKindConnect}
+	unavailable := Result{Outcome: OutcomeSuccess, StatusCode: http.StatusBadGateway}
+	throttled := Result{Outcome: OutcomeSuccess, StatusCode: http.StatusTooManyRequests, Throttled: true}
+
+	t.Run("should switch after consecutive failures and successes", func(t *testing.T) {
+		tr := newFailingTracker(KindHTTP, FailingPolicy{Interval: "5s", Failures: 2, Successes: 2})
+
+		assert.Equal(t, 5*time.Second, tr.interval)
+		assert.False(t, tr.observe(testName, failure))
+		assert.False(t, tr.observe(testName, ok))
+		assert.False(t, tr.observe(testName, failure))
+		assert.True(t, tr.observe(testName, unavailable))
+		assert.True(t, tr.observe(testName, ok))
+		assert.True(t, tr.observe(testName, failure))
+		assert.True(t, tr.observe(testName, ok))
+		assert.False(t, tr.observe(testName, ok))
+	})
+
+	t.Run("should switch after single result by default", func(t *testing.T) {
+		tr := newFailingTracker(KindHTTP, FailingPolicy{Interval: "5s"})
+
+		assert.True(t, tr.observe(testName, unavailable))
+		assert.False(t, tr.observe(testName, ok))
+	})
+
+	t.Run("should ignore throttled scrapes", func(t *testing.T) {
+		tr := newFailingTracker(KindHTTP, FailingPolicy{Interval: "5s"})
+
+		assert.False(t, tr.observe(testName, throttled))
+		assert.True(t, tr.observe(testName, failure))
+		assert.True(t, tr.observe(testName, throttled))
+	})
+}
+
+func TestRegistry_NewWithFailing(t *testing.T) {
+	r := NewDefaultRegistry(newOKClient())
+
+	t.Run("should return error on invalid failing policy", func(t *testing.T) {
+		target := testTarget
+		target.Failing = &FailingPolicy{Interval: "soon"}
+
+		_, err := r.New(target)
+
+		require.Error(t, err)
+		assert.Regexp(t, "invalid failing interval", err)
+	})
+}
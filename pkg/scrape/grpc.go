@@ -0,0 +1,171 @@
+package scrape
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"sync/atomic"
+	"time"
+
+	"github.com/pkg/errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// KindGRPC is the kind of GRPCScraper.
+const KindGRPC Kind = "grpc"
+
+// GRPCSettings defines the settings of a grpc target. Service is the name of
+// the checked service, the overall server health if empty. TLS enables TLS,
+// InsecureSkipVerify disables the server certificate verification. Timeout
+// limits the whole check, 10s if empty.
+type GRPCSettings struct {
+	Service            string `json:"service,omitempty"`
+	TLS                bool   `json:"tls,omitempty"`
+	InsecureSkipVerify bool   `json:"insecure_skip_verify,omitempty"`
+	Timeout            string `json:"timeout,omitempty"`
+}
+
+// GRPCDetails contains the details of a grpc health check: the checked
+// service, the serving status and the code of a failed call.
+type GRPCDetails struct {
+	Service string `json:"service,omitempty"`
+	Status  string `json:"status,omitempty"`
+	Code    string `json:"code,omitempty"`
+}
+
+// GRPCScraper represents a scraper that calls grpc.health.v1.Health/Check on
+// the host:port given as the target URL.
+type GRPCScraper struct {
+	address string
+	service string
+	creds   credentials.TransportCredentials
+	timeout time.Duration
+}
+
+// grpcFactory creates GRPCScrapers.
+func grpcFactory(target Target) (Scraper, error) {
+	if err := target.validateNonHTTP(); err != nil {
+		return nil, err
+	}
+	if len(target.Assertions) > 0 {
+		return nil, errors.Errorf("assertions are not supported by %s probes", KindGRPC)
+	}
+	if err := validateAddress(target.URL); err != nil {
+		return nil, err
+	}
+
+	var settings GRPCSettings
+	if err := decodeSettings(target.Settings, &settings); err != nil {
+		return nil, err
+	}
+	timeout, err := parseTimeout(settings.Timeout)
+	if err != nil {
+		return nil, err
+	}
+	if settings.InsecureSkipVerify && !settings.TLS {
+		return nil, errors.New("insecure_skip_verify requires tls")
+	}
+
+	var creds credentials.TransportCredentials
+	if settings.TLS {
+		creds = credentials.NewTLS(&tls.Config{
+			InsecureSkipVerify: settings.InsecureSkipVerify,
+		})
+	}
+
+	return &GRPCScraper{
+		address: target.URL,
+		service: settings.Service,
+		creds:   creds,
+		timeout: timeout,
+	}, nil
+}
+
+// Scrape dials the target and checks its health. A serving status other than
+// SERVING fails the scrape with the status error kind.
+func (s *GRPCScraper) Scrape(ctx context.Context) (Result, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	// 1. Dial the target, the connection is established by the first call.
+	// grpc dials in its own goroutine and may reconnect during the call, only
+	// the first connect time is kept.
+	var connect int64
+	dial := func(ctx context.Context, addr string) (net.Conn, error) {
+		start := time.Now()
+		conn, err := (&net.Dialer{}).DialContext(ctx, "tcp", addr)
+		atomic.CompareAndSwapInt64(&connect, 0, int64(time.Since(start)))
+		return conn, err
+	}
+	opts := []grpc.DialOption{grpc.WithContextDialer(dial)}
+	if s.creds != nil {
+		opts = append(opts, grpc.WithTransportCredentials(s.creds))
+	} else {
+		opts = append(opts, grpc.WithInsecure())
+	}
+
+	start := time.Now()
+	conn, err := grpc.DialContext(ctx, s.address, opts...)
+	if err != nil {
+		return Result{}, newError(
+			ErrorKindInvalidURL,
+			errors.Wrapf(err, "failed to dial %s", s.address),
+		)
+	}
+	defer conn.Close()
+
+	// 2. Call the health check
+	var p peer.Peer
+	resp, err := healthpb.NewHealthClient(conn).Check(
+		ctx,
+		&healthpb.HealthCheckRequest{Service: s.service},
+		grpc.Peer(&p),
+	)
+	if err != nil {
+		return Result{}, newError(
+			classifyGRPCError(err),
+			errors.Wrapf(err, "health check failed for %s", s.address),
+		)
+	}
+
+	// 3. Assemble the result
+	connectTime := time.Duration(atomic.LoadInt64(&connect))
+	m := Result{
+		Outcome:        OutcomeSuccess,
+		ResponseTimeMs: int(time.Since(start).Milliseconds()),
+		ConnectTimeMs:  int(connectTime.Milliseconds()),
+		Details: &Details{GRPC: &GRPCDetails{
+			Service: s.service,
+			Status:  resp.GetStatus().String(),
+		}},
+		CreatedAt: time.Now(),
+	}
+	if info, ok := p.AuthInfo.(credentials.TLSInfo); ok {
+		m.Certificate = leafCertificate(&info.State)
+	}
+	if resp.GetStatus() != healthpb.HealthCheckResponse_SERVING {
+		m.Outcome = OutcomeFailure
+		m.ErrorKind = ErrorKindStatus
+		m.ErrorMessage = "service " + s.service + " of " + s.address +
+			" is " + resp.GetStatus().String()
+	}
+
+	return m, nil
+}
+
+// classifyGRPCError returns the kind of an error returned by a grpc call.
+func classifyGRPCError(err error) ErrorKind {
+	switch status.Code(err) {
+	case codes.DeadlineExceeded:
+		return ErrorKindTimeout
+	case codes.Unavailable:
+		return ErrorKindConnect
+	default:
+		return ErrorKindStatus
+	}
+}
@@ -0,0 +1,180 @@
+package scrape
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+func TestGRPCFactory(t *testing.T) {
+	tests := []struct {
+		name    string
+		target  Target
+		wantErr string
+	}{
+		{
+			name:    "should return error on invalid address",
+			target:  Target{URL: "grpc://localhost"},
+			wantErr: "invalid port",
+		},
+		{
+			name: "should return error on assertions",
+			target: Target{
+				URL:        "localhost:50051",
+				Assertions: []Assertion{{Type: AssertionContains, Value: "ok"}},
+			},
+			wantErr: "not supported",
+		},
+		{
+			name: "should return error on insecure skip verify without tls",
+			target: Target{
+				URL:      "localhost:50051",
+				Settings: json.RawMessage(`{"insecure_skip_verify":true}`),
+			},
+			wantErr: "requires tls",
+		},
+		{
+			name: "should create scraper",
+			target: Target{
+				URL:      "localhost:50051",
+				Settings: json.RawMessage(`{"service":"orders","tls":true}`),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			target := tt.target
+			target.Kind = KindGRPC
+
+			s, err := grpcFactory(target)
+
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Regexp(t, tt.wantErr, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.IsType(t, &GRPCScraper{}, s)
+		})
+	}
+}
+
+func TestGRPCScraper_Scrape(t *testing.T) {
+	hs := health.NewServer()
+	hs.SetServingStatus("orders", healthpb.HealthCheckResponse_SERVING)
+	hs.SetServingStatus("billing", healthpb.HealthCheckResponse_NOT_SERVING)
+	addr := startGRPCServer(t, hs)
+
+	tests := []struct {
+		name        string
+		settings    string
+		wantOutcome Outcome
+		wantKind    ErrorKind
+		wantStatus  string
+	}{
+		{
+			name:        "should check server health",
+			settings:    `{}`,
+			wantOutcome: OutcomeSuccess,
+			wantStatus:  "SERVING",
+		},
+		{
+			name:        "should check service health",
+			settings:    `{"service":"orders"}`,
+			wantOutcome: OutcomeSuccess,
+			wantStatus:  "SERVING",
+		},
+		{
+			name:        "should fail when service is not serving",
+			settings:    `{"service":"billing"}`,
+			wantOutcome: OutcomeFailure,
+			wantKind:    ErrorKindStatus,
+			wantStatus:  "NOT_SERVING",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := newTestGRPCScraper(t, addr, tt.settings).Scrape(context.Background())
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantOutcome, res.Outcome, res.ErrorMessage)
+			assert.Equal(t, tt.wantKind, res.ErrorKind)
+			require.NotNil(t, res.Details.GRPC)
+			assert.Equal(t, tt.wantStatus, res.Details.GRPC.Status)
+			assert.Nil(t, res.Certificate)
+		})
+	}
+
+	t.Run("should return error on unknown service", func(t *testing.T) {
+		_, err := newTestGRPCScraper(t, addr, `{"service":"unknown"}`).Scrape(context.Background())
+
+		require.Error(t, err)
+		assert.Equal(t, ErrorKindStatus, kindOf(err))
+	})
+
+	t.Run("should return connect error", func(t *testing.T) {
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		closedAddr := l.Addr().String()
+		require.NoError(t, l.Close())
+
+		_, err = newTestGRPCScraper(t, closedAddr, `{}`).Scrape(context.Background())
+
+		require.Error(t, err)
+		assert.Equal(t, ErrorKindConnect, kindOf(err))
+	})
+
+	t.Run("should check health over tls", func(t *testing.T) {
+		// borrow the test certificate of httptest.
+		ts := httptest.NewTLSServer(http.NotFoundHandler())
+		cert := ts.TLS.Certificates[0]
+		ts.Close()
+		tlsAddr := startGRPCServer(
+			t, hs, grpc.Creds(credentials.NewServerTLSFromCert(&cert)),
+		)
+
+		res, err := newTestGRPCScraper(
+			t, tlsAddr, `{"tls":true,"insecure_skip_verify":true}`,
+		).Scrape(context.Background())
+
+		require.NoError(t, err)
+		assert.Equal(t, OutcomeSuccess, res.Outcome)
+		require.NotNil(t, res.Certificate)
+		assert.Contains(t, res.Certificate.SANs, "example.com")
+	})
+}
+
+func newTestGRPCScraper(t *testing.T, addr, settings string) Scraper {
+	s, err := grpcFactory(Target{
+		Kind: KindGRPC, URL: addr, Settings: json.RawMessage(settings),
+	})
+	require.NoError(t, err)
+	return s
+}
+
+// startGRPCServer starts a local grpc server with the given health server.
+func startGRPCServer(
+	t *testing.T, hs healthpb.HealthServer, opts ...grpc.ServerOption,
+) string {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	srv := grpc.NewServer(opts...)
+	healthpb.RegisterHealthServer(srv, hs)
+	go func() { _ = srv.Serve(l) }()
+	t.Cleanup(srv.Stop)
+
+	return l.Addr().String()
+}
@@ -0,0 +1,99 @@
+package scrape
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// jsonPath is a parsed subset of JSONPath: the root followed by child names
+// and array indexes, e.g. $.data[0].name or $['data'][0].
+type jsonPath []pathStep
+
+// pathStep is either a child name or an array index.
+type pathStep struct {
+	key     string
+	index   int
+	isIndex bool
+}
+
+// parseJSONPath parses the given path.
+func parseJSONPath(path string) (jsonPath, error) {
+	if !strings.HasPrefix(path, "$") {
+		return nil, fmt.Errorf("json path %q must start with $", path)
+	}
+
+	var steps jsonPath
+	rest := path[1:]
+	for len(rest) > 0 {
+		switch rest[0] {
+		case '.':
+			end := strings.IndexAny(rest[1:], ".[")
+			if end < 0 {
+				end = len(rest) - 1
+			}
+			key := rest[1 : end+1]
+			if key == "" {
+				return nil, fmt.Errorf("json path %q has an empty name", path)
+			}
+			steps = append(steps, pathStep{key: key})
+			rest = rest[end+1:]
+		case '[':
+			end := strings.IndexByte(rest, ']')
+			if end < 0 {
+				return nil, fmt.Errorf("json path %q has an unclosed bracket", path)
+			}
+			step, err := parseBracket(rest[1:end])
+			if err != nil {
+				return nil, fmt.Errorf("json path %q: %s", path, err)
+			}
+			steps = append(steps, step)
+			rest = rest[end+1:]
+		default:
+			return nil, fmt.Errorf(
+				"json path %q has unexpected character %q", path, rest[0],
+			)
+		}
+	}
+	return steps, nil
+}
+
+// parseBracket parses the content of brackets: an array index or a quoted
+// child name.
+func parseBracket(s string) (pathStep, error) {
+	if len(s) >= 2 && (s[0] == '\'' || s[0] == '"') && s[len(s)-1] == s[0] {
+		return pathStep{key: s[1 : len(s)-1]}, nil
+	}
+
+	index, err := strconv.Atoi(s)
+	if err != nil || index < 0 {
+		return pathStep{}, fmt.Errorf("invalid index %q", s)
+	}
+	return pathStep{index: index, isIndex: true}, nil
+}
+
+// lookup returns the value at the path in the given decoded JSON document
+// and reports if the value exists.
+func (p jsonPath) lookup(doc interface{}) (interface{}, bool) {
+	current := doc
+	for _, step := range p {
+		if step.isIndex {
+			arr, ok := current.([]interface{})
+			if !ok || step.index >= len(arr) {
+				return nil, false
+			}
+			current = arr[step.index]
+			continue
+		}
+
+		obj, ok := current.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		current, ok = obj[step.key]
+		if !ok {
+			return nil, false
+		}
+	}
+	return current, true
+}
@@ -0,0 +1,38 @@
+package scrape
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseJSONPath(t *testing.T) {
+	t.Run("should parse names and indexes", func(t *testing.T) {
+		path, err := parseJSONPath(`$.data[0]['first name'].id`)
+
+		require.NoError(t, err)
+		assert.Equal(t, jsonPath{
+			{key: "data"},
+			{index: 0, isIndex: true},
+			{key: "first name"},
+			{key: "id"},
+		}, path)
+	})
+
+	t.Run("should parse root", func(t *testing.T) {
+		path, err := parseJSONPath("$")
+
+		require.NoError(t, err)
+		assert.Empty(t, path)
+	})
+
+	for _, invalid := range []string{"data", "$.", "$..a", "$[0", "$[-1]", "$[a]", "$a"} {
+		invalid := invalid
+		t.Run("should return error on "+invalid, func(t *testing.T) {
+			_, err := parseJSONPath(invalid)
+
+			assert.Error(t, err)
+		})
+	}
+}
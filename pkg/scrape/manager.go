@@ -1,8 +1,10 @@
 package scrape
 
 import (
+	"context"
 	"fmt"
 	"net/http"
+	"sync"
 	"time"
 )
 
@@ -10,72 +12,126 @@ type httpClient interface {
 	Do(req *http.Request) (*http.Response, error)
 }
 
-// InMemoryManager provides methods to manage scrapers in memory.
+// InMemoryManager provides methods to manage scrapers in memory. Scrapers are
+// created by the registry according to the target kind and scraped by the
+// scheduler. InMemoryManager is safe for concurrent use.
 type InMemoryManager struct {
+	mu        sync.Mutex
 	producers map[string]*producer
-	client    httpClient
+	registry  *Registry
+	scheduler *Scheduler
 }
 
 // NewInMemoryManager creates a new InMemoryManager.
-func NewInMemoryManager(client httpClient) *InMemoryManager {
+func NewInMemoryManager(registry *Registry, scheduler *Scheduler) *InMemoryManager {
 	return &InMemoryManager{
 		producers: make(map[string]*producer),
-		client:    client,
+		registry:  registry,
+		scheduler: scheduler,
 	}
 }
 
-// Run creates a new scraper and runs the scraping routine.
-// NOT THREAD SAFE!
-func (m *InMemoryManager) Run(
-	name, url string, scrapeInterval time.Duration,
-) (<-chan Result, error) {
-	_, exists := m.producers[name]
-	if exists {
-		return nil, fmt.Errorf("scraper %s does already exist", name)
-	}
+// Validate checks that a scraper can be created for the given target.
+func (m *InMemoryManager) Validate(target Target) error {
+	_, err := m.registry.New(target)
+	return err
+}
 
-	s := newHTTPScraper(m.client, url)
-	p := newProducer(name, s, scrapeInterval)
-	m.producers[name] = p
+// Run creates a new scraper and schedules its scrapes. The first scrape
+// happens about the scrape interval later, at the position of the scraper
+// within the interval.
+func (m *InMemoryManager) Run(name string, target Target) (<-chan Result, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.start(name, target, func(p *producer) time.Time {
+		return p.align(time.Now().Add(target.Interval))
+	})
+}
 
-	go p.run()
+// RunDelayed creates a new scraper and schedules its scrapes. The first
+// scrape happens after the given delay.
+func (m *InMemoryManager) RunDelayed(
+	name string, target Target, delay time.Duration,
+) (<-chan Result, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
 
-	return p.resCh, nil
+	return m.start(name, target, func(*producer) time.Time {
+		return time.Now().Add(delay)
+	})
 }
 
 // Update updates the scraper associated with the given name.
-// NOT THREAD SAFE!
 func (m *InMemoryManager) Update(
-	name, url string, scrapeInterval time.Duration,
+	name string, target Target,
 ) (<-chan Result, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
 	p, exists := m.producers[name]
 	if !exists {
 		return nil, fmt.Errorf("scraper %s does not exist", name)
 	}
 
-	// stop and delete the existing scraper
-	delete(m.producers, "name")
-	p.stopCh <- struct{}{}
-	// create a new scraper
-	s := newHTTPScraper(m.client, url)
-	p = newProducer(name, s, scrapeInterval)
-	m.producers[name] = p
-
-	go p.run()
+	s, err := m.registry.New(target)
+	if err != nil {
+		return nil, err
+	}
 
+	// stop the existing scraper and replace it with a new one
+	m.scheduler.remove(p)
+	p = m.scheduler.newProducer(name, s, target)
+	m.producers[name] = p
+	m.scheduler.schedule(p, p.align(time.Now().Add(target.Interval)))
 	return p.resCh, nil
 }
 
 // Stop stops the scraper associated with the given name and removes it
 // from the list of scrapers.
-// NOT THREAD SAFE!
 func (m *InMemoryManager) Stop(name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
 	p, exists := m.producers[name]
 	if !exists {
 		return fmt.Errorf("scraper %s does not exist", name)
 	}
 
-	delete(m.producers, "name")
-	p.stopCh <- struct{}{}
+	delete(m.producers, name)
+	m.scheduler.remove(p)
 	return nil
 }
+
+// Shutdown stops all scrapers. Running scrapes are finished and their results
+// are published unless the context is done first. The result channels of all
+// scrapers are closed.
+func (m *InMemoryManager) Shutdown(ctx context.Context) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.producers = make(map[string]*producer)
+	return m.scheduler.Shutdown(ctx)
+}
+
+// start creates a new scraper for the target, registers it under the given
+// name and schedules the first scrape at the time returned by first. The
+// caller must hold the lock.
+func (m *InMemoryManager) start(
+	name string, target Target, first func(p *producer) time.Time,
+) (<-chan Result, error) {
+	_, exists := m.producers[name]
+	if exists {
+		return nil, fmt.Errorf("scraper %s does already exist", name)
+	}
+
+	s, err := m.registry.New(target)
+	if err != nil {
+		return nil, err
+	}
+
+	p := m.scheduler.newProducer(name, s, target)
+	m.producers[name] = p
+	m.scheduler.schedule(p, first(p))
+	return p.resCh, nil
+}
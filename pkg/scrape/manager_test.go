@@ -0,0 +1,252 @@
+package scrape
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testName     = "test_scraper"
+	testInterval = time.Millisecond
+)
+
+var testTarget = Target{URL: "https://example.com", Interval: testInterval}
+
+func TestInMemoryManager_Run(t *testing.T) {
+	t.Run("should return error when scraper already exists", func(t *testing.T) {
+		m := newTestManager(t)
+		_, err := m.Run(testName, testTarget)
+		require.NoError(t, err)
+		defer m.Stop(testName)
+
+		_, err = m.Run(testName, testTarget)
+
+		require.Error(t, err)
+		assert.Regexp(t, testName, err)
+	})
+
+	t.Run("should produce results", func(t *testing.T) {
+		m := newTestManager(t)
+		resCh, err := m.Run(testName, testTarget)
+		require.NoError(t, err)
+		defer m.Stop(testName)
+
+		res := <-resCh
+
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+	})
+}
+
+func TestInMemoryManager_Update(t *testing.T) {
+	t.Run("should return error when scraper does not exist", func(t *testing.T) {
+		m := newTestManager(t)
+
+		_, err := m.Update(testName, testTarget)
+
+		require.Error(t, err)
+		assert.Regexp(t, testName, err)
+	})
+
+	t.Run("should replace existing scraper", func(t *testing.T) {
+		m := newTestManager(t)
+		oldCh, err := m.Run(
+			testName, Target{URL: "https://example.com", Interval: time.Hour},
+		)
+		require.NoError(t, err)
+
+		newCh, err := m.Update(testName, testTarget)
+		require.NoError(t, err)
+		defer m.Stop(testName)
+
+		assertClosed(t, oldCh)
+		res := <-newCh
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+	})
+}
+
+func TestInMemoryManager_Stop(t *testing.T) {
+	t.Run("should return error when scraper does not exist", func(t *testing.T) {
+		m := newTestManager(t)
+
+		err := m.Stop(testName)
+
+		require.Error(t, err)
+		assert.Regexp(t, testName, err)
+	})
+
+	t.Run("should allow to recreate stopped scraper", func(t *testing.T) {
+		m := newTestManager(t)
+		resCh, err := m.Run(testName, testTarget)
+		require.NoError(t, err)
+
+		require.NoError(t, m.Stop(testName))
+		assertClosed(t, resCh)
+
+		_, err = m.Run(testName, testTarget)
+		assert.NoError(t, err)
+		assert.NoError(t, m.Stop(testName))
+	})
+
+	t.Run("should not block when results are not consumed", func(t *testing.T) {
+		m := newTestManager(t)
+		resCh, err := m.Run(testName, testTarget)
+		require.NoError(t, err)
+		// let the producer block on publishing a result.
+		time.Sleep(10 * testInterval)
+
+		done := make(chan struct{})
+		go func() {
+			_ = m.Stop(testName)
+			close(done)
+		}()
+
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Fatal("stop is blocked")
+		}
+		assertClosed(t, resCh)
+	})
+}
+
+func TestInMemoryManager_Cancel(t *testing.T) {
+	t.Run("should cancel in-flight scrape on stop", func(t *testing.T) {
+		started := make(chan struct{})
+		r := NewRegistry()
+		require.NoError(t, r.Register("hanging", func(Target) (Scraper, error) {
+			return &ctxScraperMock{started: started}, nil
+		}))
+		s, err := NewScheduler(context.Background(), 1, 0, Pipeline{})
+		require.NoError(t, err)
+		defer abort(s)
+		m := NewInMemoryManager(r, s)
+		resCh, err := m.RunDelayed(testName, Target{Kind: "hanging", Interval: time.Hour}, 0)
+		require.NoError(t, err)
+		<-started
+
+		require.NoError(t, m.Stop(testName))
+
+		assertClosed(t, resCh)
+	})
+}
+
+func TestInMemoryManager_Shutdown(t *testing.T) {
+	t.Run("should close results of all scrapers", func(t *testing.T) {
+		m := newTestManager(t)
+		first, err := m.Run("first", testTarget)
+		require.NoError(t, err)
+		second, err := m.Run("second", Target{URL: "https://example.com", Interval: time.Hour})
+		require.NoError(t, err)
+
+		require.NoError(t, m.Shutdown(context.Background()))
+
+		assertClosed(t, first)
+		assertClosed(t, second)
+		assert.Error(t, m.Stop("first"))
+	})
+}
+
+func TestInMemoryManager_Concurrent(t *testing.T) {
+	const (
+		workers    = 10
+		iterations = 50
+	)
+
+	hammer := func(m *InMemoryManager, nameFn func(worker int) string) {
+		var wg sync.WaitGroup
+		for w := 0; w < workers; w++ {
+			wg.Add(1)
+			go func(w int) {
+				defer wg.Done()
+				name := nameFn(w)
+				for i := 0; i < iterations; i++ {
+					// errors are expected when operations on the same name
+					// interleave, only consistency of the manager matters.
+					if resCh, err := m.Run(name, testTarget); err == nil {
+						go drain(resCh)
+					}
+					if resCh, err := m.Update(name, testTarget); err == nil {
+						go drain(resCh)
+					}
+					_ = m.Stop(name)
+				}
+			}(w)
+		}
+		wg.Wait()
+	}
+
+	t.Run("same name", func(t *testing.T) {
+		m := newTestManager(t)
+
+		hammer(m, func(int) string { return testName })
+
+		assertStoppable(t, m, testName)
+	})
+
+	t.Run("different names", func(t *testing.T) {
+		m := newTestManager(t)
+
+		hammer(m, func(w int) string { return fmt.Sprintf("%s_%d", testName, w) })
+
+		for w := 0; w < workers; w++ {
+			assertStoppable(t, m, fmt.Sprintf("%s_%d", testName, w))
+		}
+	})
+}
+
+// assertStoppable checks that the scraper with the given name does not exist
+// and can be created and stopped again.
+func assertStoppable(t *testing.T, m *InMemoryManager, name string) {
+	_, err := m.Run(name, testTarget)
+	require.NoError(t, err)
+	require.NoError(t, m.Stop(name))
+	assert.Error(t, m.Stop(name))
+}
+
+// newTestManager returns a new manager with a scheduler closed on the test
+// cleanup.
+func newTestManager(t *testing.T) *InMemoryManager {
+	s, err := NewScheduler(context.Background(), 4, 0, Pipeline{})
+	require.NoError(t, err)
+	t.Cleanup(func() { abort(s) })
+	return NewInMemoryManager(NewDefaultRegistry(newOKClient()), s)
+}
+
+func assertClosed(t *testing.T, resCh <-chan Result) {
+	timeout := time.After(time.Second)
+	for {
+		select {
+		case _, ok := <-resCh:
+			if !ok {
+				return
+			}
+		case <-timeout:
+			t.Fatal("result channel is not closed")
+		}
+	}
+}
+
+func drain(resCh <-chan Result) {
+	for range resCh {
+	}
+}
+
+func newOKClient() httpClient {
+	return &clientMock{
+		doMock: func(req *http.Request) (*http.Response, error) {
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Body:       ioutil.NopCloser(strings.NewReader("7 bytes")),
+			}, nil
+		},
+	}
+}
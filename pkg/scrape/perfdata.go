@@ -0,0 +1,140 @@
+package scrape
+
+import (
+	"strconv"
+	"strings"
+)
+
+// PerfSample represents a single sample of the performance data printed by a
+// Nagios-style check: 'label'=value[UOM];[warn];[crit];[min];[max].
+// Warn and crit are kept as is, since they are ranges rather than numbers.
+type PerfSample struct {
+	Label string   `json:"label"`
+	Value float64  `json:"value"`
+	Unit  string   `json:"unit,omitempty"`
+	Warn  string   `json:"warn,omitempty"`
+	Crit  string   `json:"crit,omitempty"`
+	Min   *float64 `json:"min,omitempty"`
+	Max   *float64 `json:"max,omitempty"`
+}
+
+// parseCheckOutput splits the output of a Nagios-style check into the status
+// text, the first line before the pipe, and the performance data. The
+// performance data follows the pipe in the first line and the pipe in the
+// long output, if any. Invalid and undetermined samples are skipped.
+func parseCheckOutput(output string) (string, []PerfSample) {
+	lines := strings.Split(strings.TrimRight(output, "\n"), "\n")
+
+	text, perf := splitPerfData(lines[0])
+	perfData := []string{perf}
+	for i := 1; i < len(lines); i++ {
+		if j := strings.Index(lines[i], "|"); j >= 0 {
+			perfData = append(perfData, lines[i][j+1:])
+			perfData = append(perfData, lines[i+1:]...)
+			break
+		}
+	}
+
+	var samples []PerfSample
+	for _, token := range splitPerfTokens(strings.Join(perfData, " ")) {
+		if s, ok := parsePerfSample(token); ok {
+			samples = append(samples, s)
+		}
+	}
+	return strings.TrimSpace(text), samples
+}
+
+// splitPerfData splits the given line at the first pipe.
+func splitPerfData(line string) (string, string) {
+	i := strings.Index(line, "|")
+	if i < 0 {
+		return line, ""
+	}
+	return line[:i], strings.TrimSpace(line[i+1:])
+}
+
+// splitPerfTokens splits the performance data into samples separated by
+// whitespace. Whitespace in quoted labels does not separate samples.
+func splitPerfTokens(perf string) []string {
+	var (
+		tokens []string
+		token  strings.Builder
+		quoted bool
+	)
+	for _, r := range perf {
+		switch {
+		case r == '\'':
+			quoted = !quoted
+			token.WriteRune(r)
+		case !quoted && (r == ' ' || r == '\t' || r == '\n'):
+			if token.Len() > 0 {
+				tokens = append(tokens, token.String())
+				token.Reset()
+			}
+		default:
+			token.WriteRune(r)
+		}
+	}
+	if token.Len() > 0 {
+		tokens = append(tokens, token.String())
+	}
+	return tokens
+}
+
+// parsePerfSample parses a single sample of the performance data.
+func parsePerfSample(token string) (PerfSample, bool) {
+	i := strings.LastIndex(token, "=")
+	if i <= 0 {
+		return PerfSample{}, false
+	}
+	label := token[:i]
+	if len(label) >= 2 && strings.HasPrefix(label, "'") && strings.HasSuffix(label, "'") {
+		label = strings.ReplaceAll(label[1:len(label)-1], "''", "'")
+	}
+
+	fields := strings.Split(token[i+1:], ";")
+	value, unit := splitUnit(fields[0])
+	v, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		// also skips the undetermined value U.
+		return PerfSample{}, false
+	}
+
+	s := PerfSample{Label: label, Value: v, Unit: unit}
+	if len(fields) > 1 {
+		s.Warn = fields[1]
+	}
+	if len(fields) > 2 {
+		s.Crit = fields[2]
+	}
+	if len(fields) > 3 {
+		s.Min = parseOptionalFloat(fields[3])
+	}
+	if len(fields) > 4 {
+		s.Max = parseOptionalFloat(fields[4])
+	}
+	return s, true
+}
+
+// splitUnit splits the value of a sample into the number and the unit of
+// measurement.
+func splitUnit(value string) (string, string) {
+	i := strings.IndexFunc(value, func(r rune) bool {
+		return !(r >= '0' && r <= '9' || r == '.' || r == '-' || r == '+' ||
+			r == 'e' || r == 'E')
+	})
+	if i < 0 {
+		return value, ""
+	}
+	return value[:i], value[i:]
+}
+
+// parseOptionalFloat returns the parsed number, or nil if it is empty or
+// invalid.
+func parseOptionalFloat(value string) *float64 {
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return nil
+	}
+	return &f
+}
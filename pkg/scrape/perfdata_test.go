@@ -0,0 +1,73 @@
+package scrape
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseCheckOutput(t *testing.T) {
+	f := func(v float64) *float64 { return &v }
+
+	tests := []struct {
+		name         string
+		output       string
+		wantText     string
+		wantPerfData []PerfSample
+	}{
+		{
+			name:     "should parse output without perfdata",
+			output:   "PING OK - Packet loss = 0%\n",
+			wantText: "PING OK - Packet loss = 0%",
+		},
+		{
+			name:     "should parse perfdata of first line",
+			output:   "DISK OK - free space: / 3326 MB (56%); | /=2643MB;5948;5958;0;5968\n",
+			wantText: "DISK OK - free space: / 3326 MB (56%);",
+			wantPerfData: []PerfSample{{
+				Label: "/", Value: 2643, Unit: "MB", Warn: "5948", Crit: "5958",
+				Min: f(0), Max: f(5968),
+			}},
+		},
+		{
+			name:     "should parse several samples and quoted labels",
+			output:   "OK | time=0.006s;;;0.000 'packet loss'=0%;20:;@50 size=128B",
+			wantText: "OK",
+			wantPerfData: []PerfSample{
+				{Label: "time", Value: 0.006, Unit: "s", Min: f(0)},
+				{Label: "packet loss", Value: 0, Unit: "%", Warn: "20:", Crit: "@50"},
+				{Label: "size", Value: 128, Unit: "B"},
+			},
+		},
+		{
+			name: "should parse perfdata of long output",
+			output: "DISK OK | /=2643MB\n" +
+				"/ 15272 MB (77%);\n" +
+				"/boot 68 MB (69%); | /boot=68MB;88;93;0;98\n" +
+				"/home=69357MB;253404;253409;0;253414\n",
+			wantText: "DISK OK",
+			wantPerfData: []PerfSample{
+				{Label: "/", Value: 2643, Unit: "MB"},
+				{Label: "/boot", Value: 68, Unit: "MB", Warn: "88", Crit: "93", Min: f(0), Max: f(98)},
+				{Label: "/home", Value: 69357, Unit: "MB", Warn: "253404", Crit: "253409", Min: f(0), Max: f(253414)},
+			},
+		},
+		{
+			name:     "should skip undetermined and invalid samples",
+			output:   "UNKNOWN | load=U;1;2 broken =5 c=12c",
+			wantText: "UNKNOWN",
+			wantPerfData: []PerfSample{
+				{Label: "c", Value: 12, Unit: "c"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			text, perfData := parseCheckOutput(tt.output)
+
+			assert.Equal(t, tt.wantText, text)
+			assert.Equal(t, tt.wantPerfData, perfData)
+		})
+	}
+}
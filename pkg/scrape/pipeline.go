@@ -0,0 +1,134 @@
+package scrape
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// OverflowPolicy defines what happens to a scrape result when the result
+// buffer of a scraper is full.
+type OverflowPolicy string
+
+// Possible overflow policies.
+const (
+	// OverflowBlock waits until the consumer takes a result from the buffer.
+	OverflowBlock OverflowPolicy = "block"
+	// OverflowDropOldest drops the oldest buffered result.
+	OverflowDropOldest OverflowPolicy = "drop-oldest"
+	// OverflowDropNewest drops the new result.
+	OverflowDropNewest OverflowPolicy = "drop-newest"
+)
+
+// Pipeline defines how scrape results are buffered between the scheduler and
+// the consumer of every scraper. Capacity is the number of buffered results,
+// Overflow defines what happens when the buffer is full, block if empty.
+type Pipeline struct {
+	Capacity int
+	Overflow OverflowPolicy
+}
+
+// Validate checks that the pipeline is well-formed.
+func (p Pipeline) Validate() error {
+	if p.Capacity < 0 {
+		return errors.Errorf("result buffer capacity %d must not be negative", p.Capacity)
+	}
+	switch p.overflow() {
+	case OverflowBlock:
+	case OverflowDropOldest, OverflowDropNewest:
+		if p.Capacity == 0 {
+			return errors.Errorf("overflow policy %s requires a result buffer", p.Overflow)
+		}
+	default:
+		return errors.Errorf("unknown overflow policy %q", p.Overflow)
+	}
+	return nil
+}
+
+// overflow returns the overflow policy of the pipeline.
+func (p Pipeline) overflow() OverflowPolicy {
+	if p.Overflow == "" {
+		return OverflowBlock
+	}
+	return p.Overflow
+}
+
+// PipelineStats contains the counters of scrape results passed to the
+// consumers since the start. Delayed results waited for a slow consumer for
+// DelayMs in total.
+type PipelineStats struct {
+	Published uint64 `json:"published"`
+	Dropped   uint64 `json:"dropped"`
+	Delayed   uint64 `json:"delayed"`
+	DelayMs   int64  `json:"delay_ms"`
+}
+
+// pipelineCounters counts scrape results, it is safe for concurrent use.
+type pipelineCounters struct {
+	published uint64
+	dropped   uint64
+	delayed   uint64
+	delay     int64
+}
+
+// stats returns a snapshot of the counters.
+func (c *pipelineCounters) stats() PipelineStats {
+	return PipelineStats{
+		Published: atomic.LoadUint64(&c.published),
+		Dropped:   atomic.LoadUint64(&c.dropped),
+		Delayed:   atomic.LoadUint64(&c.delayed),
+		DelayMs:   time.Duration(atomic.LoadInt64(&c.delay)).Milliseconds(),
+	}
+}
+
+// publish publishes the result of the producer according to the overflow
+// policy. Results of stopped producers are discarded, a blocked publish is
+// abandoned if the producer is stopped or the given abort channel is closed.
+func (c *pipelineCounters) publish(
+	policy OverflowPolicy, p *producer, res Result, abortCh <-chan struct{},
+) {
+	select {
+	case <-p.stopCh:
+		return
+	default:
+	}
+
+	select {
+	case p.resCh <- res:
+		atomic.AddUint64(&c.published, 1)
+		return
+	default:
+	}
+
+	switch policy {
+	case OverflowDropNewest:
+		atomic.AddUint64(&c.dropped, 1)
+	case OverflowDropOldest:
+		for {
+			select {
+			case <-p.resCh:
+				atomic.AddUint64(&c.dropped, 1)
+			default:
+			}
+			select {
+			case p.resCh <- res:
+				atomic.AddUint64(&c.published, 1)
+				return
+			default:
+			}
+		}
+	default:
+		atomic.AddUint64(&c.delayed, 1)
+		start := time.Now()
+		defer func() {
+			atomic.AddInt64(&c.delay, int64(time.Since(start)))
+		}()
+		select {
+		case p.resCh <- res:
+			atomic.AddUint64(&c.published, 1)
+		case <-p.stopCh:
+		case <-abortCh:
+		}
+	}
+}
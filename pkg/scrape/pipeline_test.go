@@ -0,0 +1,140 @@
+package scrape
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPipeline_Validate(t *testing.T) {
+	tests := []struct {
+		name     string
+		pipeline Pipeline
+		wantErr  string
+	}{
+		{
+			name:     "should return error on negative capacity",
+			pipeline: Pipeline{Capacity: -1},
+			wantErr:  "must not be negative",
+		},
+		{
+			name:     "should return error on unknown overflow policy",
+			pipeline: Pipeline{Capacity: 1, Overflow: "drop-all"},
+			wantErr:  "unknown overflow policy",
+		},
+		{
+			name:     "should return error on dropping without buffer",
+			pipeline: Pipeline{Overflow: OverflowDropOldest},
+			wantErr:  "requires a result buffer",
+		},
+		{
+			name:     "should accept blocking without buffer",
+			pipeline: Pipeline{},
+		},
+		{
+			name:     "should accept dropping with buffer",
+			pipeline: Pipeline{Capacity: 4, Overflow: OverflowDropNewest},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.pipeline.Validate()
+
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Regexp(t, tt.wantErr, err)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestPipelineCounters_Publish(t *testing.T) {
+	publishAll := func(c *pipelineCounters, policy OverflowPolicy, p *producer) {
+		for code := 1; code <= 3; code++ {
+			c.publish(policy, p, Result{StatusCode: code}, nil)
+		}
+	}
+
+	t.Run("should drop newest results when buffer is full", func(t *testing.T) {
+		c := &pipelineCounters{}
+		p := newProducer(context.Background(), testName, nil, testTarget, 2)
+
+		publishAll(c, OverflowDropNewest, p)
+
+		assert.Equal(t, PipelineStats{Published: 2, Dropped: 1}, c.stats())
+		assert.Equal(t, 1, (<-p.resCh).StatusCode)
+		assert.Equal(t, 2, (<-p.resCh).StatusCode)
+	})
+
+	t.Run("should drop oldest results when buffer is full", func(t *testing.T) {
+		c := &pipelineCounters{}
+		p := newProducer(context.Background(), testName, nil, testTarget, 2)
+
+		publishAll(c, OverflowDropOldest, p)
+
+		assert.Equal(t, PipelineStats{Published: 3, Dropped: 1}, c.stats())
+		assert.Equal(t, 2, (<-p.resCh).StatusCode)
+		assert.Equal(t, 3, (<-p.resCh).StatusCode)
+	})
+
+	t.Run("should count delayed results", func(t *testing.T) {
+		c := &pipelineCounters{}
+		p := newProducer(context.Background(), testName, nil, testTarget, 1)
+		go func() {
+			time.Sleep(20 * time.Millisecond)
+			for range p.resCh {
+			}
+		}()
+
+		publishAll(c, OverflowBlock, p)
+		close(p.resCh)
+
+		stats := c.stats()
+		assert.Equal(t, uint64(3), stats.Published)
+		assert.Zero(t, stats.Dropped)
+		assert.GreaterOrEqual(t, stats.Delayed, uint64(1))
+		assert.GreaterOrEqual(t, stats.DelayMs, int64(10))
+	})
+
+	t.Run("should discard result of stopped producer", func(t *testing.T) {
+		c := &pipelineCounters{}
+		p := newProducer(context.Background(), testName, nil, testTarget, 1)
+		p.stop()
+
+		c.publish(OverflowBlock, p, Result{}, nil)
+
+		assert.Equal(t, PipelineStats{}, c.stats())
+		assert.Len(t, p.resCh, 0)
+	})
+}
+
+func TestScheduler_Stats(t *testing.T) {
+	t.Run("should count published results", func(t *testing.T) {
+		s, err := NewScheduler(context.Background(), 1, 0, Pipeline{Capacity: 4})
+		require.NoError(t, err)
+		defer abort(s)
+		p := s.newProducer(testName, &scraperMock{
+			scrapeMock: func() (Result, error) { return Result{}, nil },
+		}, Target{Interval: time.Hour})
+		s.schedule(p, time.Now())
+
+		<-p.resCh
+		s.remove(p)
+		// the channel is closed after the result is counted.
+		assertClosed(t, p.resCh)
+
+		assert.Equal(t, uint64(1), s.Stats().Published)
+	})
+
+	t.Run("should return error on invalid pipeline", func(t *testing.T) {
+		_, err := NewScheduler(context.Background(), 1, 0, Pipeline{Capacity: -1})
+
+		assert.Error(t, err)
+	})
+}
@@ -1,52 +1,153 @@
 package scrape
 
 import (
+	"context"
+	"hash/fnv"
 	"log"
+	"sync"
 	"time"
 )
 
 // producer is a wrapper over scraper which produce an infinite stream of
-// scraping results.
+// scraping results. Scrapes are run by the scheduler.
 type producer struct {
 	name             string
-	scraper          scraper
+	host             string
+	scraper          Scraper
 	scrapingInterval time.Duration
-	stopCh           chan struct{}
-	resCh            chan Result
+	// timeout limits every scrape, if positive.
+	timeout time.Duration
+	// offset is the deterministic position of the scrapes within the
+	// scraping interval.
+	offset time.Duration
+	// failing switches the producer to the failing interval, if set.
+	failing *failingTracker
+	// throttles is the number of consecutive throttled scrapes.
+	throttles int
+	// ctx is canceled when the producer is stopped.
+	ctx      context.Context
+	cancel   context.CancelFunc
+	stopOnce sync.Once
+	stopCh   chan struct{}
+	resCh    chan Result
+
+	// the fields below are guarded by the scheduler lock.
+	due     time.Time
+	index   int
+	running bool
+	blocked bool
+	stopped bool
 }
 
-// newProducer constructs a new producer.
-// Result and stop channels will be instantiated.
-func newProducer(name string, scraper scraper, scrapingInterval time.Duration) *producer {
-	return &producer{
+// newProducer constructs a new producer of the given target. Result and stop
+// channels will be instantiated, the result channel buffers the given number
+// of results. Scrapes are aborted when the given context is done.
+func newProducer(
+	ctx context.Context, name string, scraper Scraper, target Target, capacity int,
+) *producer {
+	ctx, cancel := context.WithCancel(ctx)
+	p := &producer{
 		name:             name,
+		host:             targetHost(target),
 		scraper:          scraper,
-		scrapingInterval: scrapingInterval,
+		scrapingInterval: target.Interval,
+		timeout:          target.Timeout,
+		offset:           jitter(name, target.Interval),
+		ctx:              ctx,
+		cancel:           cancel,
 		stopCh:           make(chan struct{}),
-		resCh:            make(chan Result),
+		resCh:            make(chan Result, capacity),
+		index:            -1,
+	}
+	if target.Failing != nil {
+		p.failing = newFailingTracker(target.kind(), *target.Failing)
+	}
+	return p
+}
+
+// scrape scrapes the target once and returns the result and the time of the
+// next scrape. Failed scrapes are returned as results with the failure
+// outcome. The interval changes while the target fails or throttles the
+// scrapes.
+func (p *producer) scrape() (Result, time.Time) {
+	ctx := p.ctx
+	if p.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, p.timeout)
+		defer cancel()
+	}
+
+	start := time.Now()
+	res, err := p.scraper.Scrape(ctx)
+	if err != nil {
+		log.Printf("scrape failed: %+v\n", err)
+		res = failedResult(err)
+	}
+
+	interval := p.nextInterval(res)
+	if interval == p.scrapingInterval {
+		return res, p.align(start.Add(interval))
+	}
+	return res, start.Add(interval)
+}
+
+// stop signals the producer to stop publishing results and cancels its
+// running scrape. It never blocks and is safe to call multiple times.
+func (p *producer) stop() {
+	p.stopOnce.Do(func() {
+		p.cancel()
+		close(p.stopCh)
+	})
+}
+
+// nextInterval returns the interval between the start of the scrape with the
+// given result and the next scrape. While the target is failing the failing
+// interval is used instead of the scraping interval. While the target
+// throttles the scrapes the interval is backed off, it returns to normal once
+// a scrape is not throttled.
+func (p *producer) nextInterval(res Result) time.Duration {
+	interval := p.scrapingInterval
+	if p.failing != nil && p.failing.observe(p.name, res) {
+		interval = p.failing.interval
+	}
+
+	if !res.Throttled {
+		p.throttles = 0
+		return interval
+	}
+
+	p.throttles++
+	retryAfter := time.Duration(res.RetryAfterMs) * time.Millisecond
+	interval = throttledInterval(interval, p.throttles, retryAfter)
+	log.Printf("scraper %s is throttled, next scrape in %s\n", p.name, interval)
+	return interval
+}
+
+// align returns the time closest to t which is offset from a multiple of the
+// scraping interval, so that producers with the same interval are spread
+// across it.
+func (p *producer) align(t time.Time) time.Time {
+	interval := int64(p.scrapingInterval)
+	if interval <= 0 {
+		return t
+	}
+	rem := (t.UnixNano() - int64(p.offset)) % interval
+	if rem < 0 {
+		rem += interval
+	}
+	if 2*rem >= interval {
+		return t.Add(time.Duration(interval - rem))
 	}
+	return t.Add(-time.Duration(rem))
 }
 
-// run runs the producer routine: after every scraping interval a web page
-// will be scraped and the result will be gathered and published to resCh.
-// The routine is terminated by the producer stop channel.
-func (p *producer) run() {
-	t := time.NewTicker(p.scrapingInterval)
-	defer t.Stop()
-
-	for {
-		select {
-		case <-p.stopCh:
-			log.Printf("shutdown producer %s\n", p.name)
-			close(p.resCh)
-			return
-		case <-t.C:
-			res, err := p.scraper.scrape()
-			if err != nil {
-				log.Printf("scrape failed: %+v\n", err)
-				continue
-			}
-			p.resCh <- res
-		}
+// jitter returns a deterministic offset within the interval for the given
+// name.
+func jitter(name string, interval time.Duration) time.Duration {
+	if interval <= 0 {
+		return 0
 	}
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(name))
+	return time.Duration(h.Sum64() % uint64(interval))
 }
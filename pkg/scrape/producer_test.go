@@ -0,0 +1,166 @@
+package scrape
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type scraperMock struct {
+	scrapeMock func() (Result, error)
+}
+
+func (s *scraperMock) Scrape(context.Context) (Result, error) {
+	return s.scrapeMock()
+}
+
+// ctxScraperMock is a scraper that blocks until the scrape context is done.
+type ctxScraperMock struct {
+	started chan struct{}
+}
+
+func (s *ctxScraperMock) Scrape(ctx context.Context) (Result, error) {
+	close(s.started)
+	<-ctx.Done()
+	kind := ErrorKindUnknown
+	if isTimeout(ctx.Err()) {
+		kind = ErrorKindTimeout
+	}
+	return Result{}, newError(kind, ctx.Err())
+}
+
+func TestProducer_Scrape(t *testing.T) {
+	t.Run("should return failed scrape", func(t *testing.T) {
+		s := &scraperMock{
+			scrapeMock: func() (Result, error) {
+				return Result{}, newError(ErrorKindDNS, assert.AnError)
+			},
+		}
+		p := newProducer(context.Background(), testName, s, Target{Interval: time.Hour}, 0)
+
+		res, due := p.scrape()
+
+		assert.Equal(t, OutcomeFailure, res.Outcome)
+		assert.Equal(t, ErrorKindDNS, res.ErrorKind)
+		assert.Equal(t, assert.AnError.Error(), res.ErrorMessage)
+		assert.False(t, res.CreatedAt.IsZero())
+		assert.WithinDuration(t, time.Now().Add(time.Hour), due, time.Hour/2)
+	})
+
+	t.Run("should align next scrape within interval", func(t *testing.T) {
+		s := &scraperMock{
+			scrapeMock: func() (Result, error) { return Result{}, nil },
+		}
+		p := newProducer(context.Background(), testName, s, Target{Interval: time.Minute}, 0)
+
+		_, due := p.scrape()
+
+		assert.Equal(t, p.offset, time.Duration(due.UnixNano()%int64(time.Minute)))
+	})
+}
+
+func TestProducer_Cancel(t *testing.T) {
+	t.Run("should cancel running scrape on stop", func(t *testing.T) {
+		started := make(chan struct{})
+		s := &ctxScraperMock{started: started}
+		p := newProducer(context.Background(), testName, s, Target{Interval: time.Hour}, 0)
+
+		done := make(chan Result)
+		go func() {
+			res, _ := p.scrape()
+			done <- res
+		}()
+		<-started
+		p.stop()
+
+		select {
+		case res := <-done:
+			assert.Equal(t, OutcomeFailure, res.Outcome)
+			assert.Regexp(t, "canceled", res.ErrorMessage)
+		case <-time.After(time.Second):
+			t.Fatal("scrape is not canceled")
+		}
+	})
+
+	t.Run("should limit scrape with target timeout", func(t *testing.T) {
+		s := &ctxScraperMock{started: make(chan struct{})}
+		p := newProducer(context.Background(), testName, s, Target{
+			Interval: time.Hour,
+			Timeout:  10 * time.Millisecond,
+		}, 0)
+
+		res, _ := p.scrape()
+
+		assert.Equal(t, OutcomeFailure, res.Outcome)
+		assert.Equal(t, ErrorKindTimeout, res.ErrorKind)
+	})
+}
+
+func TestProducer_NextInterval(t *testing.T) {
+	t.Run("should back off throttled scrapes", func(t *testing.T) {
+		p := newProducer(context.Background(), testName, nil, Target{Interval: time.Minute}, 0)
+
+		assert.Equal(t, 2*time.Minute, p.nextInterval(Result{Throttled: true}))
+		assert.Equal(t, 4*time.Minute, p.nextInterval(Result{Throttled: true}))
+		assert.Equal(t,
+			5*time.Minute,
+			p.nextInterval(Result{Throttled: true, RetryAfterMs: 300000}),
+		)
+		assert.Equal(t, time.Minute, p.nextInterval(Result{}))
+		assert.Zero(t, p.throttles)
+	})
+
+	t.Run("should use failing interval while target is failing", func(t *testing.T) {
+		p := newProducer(context.Background(), testName, nil, Target{
+			Interval: time.Hour,
+			Failing:  &FailingPolicy{Interval: "10s"},
+		}, 0)
+
+		assert.Equal(t, 10*time.Second, p.nextInterval(Result{Outcome: OutcomeFailure}))
+		assert.Equal(t, time.Hour, p.nextInterval(Result{Outcome: OutcomeSuccess}))
+	})
+	t.Run("should not treat websocket handshake as failure", func(t *testing.T) {
+		p := newProducer(context.Background(), testName, nil, Target{
+			Kind:     KindWebSocket,
+			Interval: time.Hour,
+			Failing:  &FailingPolicy{Interval: "10s"},
+		}, 0)
+		healthy := Result{Outcome: OutcomeSuccess, StatusCode: http.StatusSwitchingProtocols}
+
+		for i := 0; i < 3; i++ {
+			assert.Equal(t, time.Hour, p.nextInterval(healthy))
+		}
+		assert.Equal(t, 10*time.Second, p.nextInterval(Result{Outcome: OutcomeFailure}))
+		assert.Equal(t, time.Hour, p.nextInterval(healthy))
+	})
+
+	t.Run("should treat non-2xx http status as failure", func(t *testing.T) {
+		p := newProducer(context.Background(), testName, nil, Target{
+			Interval: time.Hour,
+			Failing:  &FailingPolicy{Interval: "10s"},
+		}, 0)
+
+		assert.Equal(t, 10*time.Second, p.nextInterval(Result{
+			Outcome: OutcomeSuccess, StatusCode: http.StatusServiceUnavailable,
+		}))
+	})
+}
+
+func TestJitter(t *testing.T) {
+	t.Run("should be deterministic", func(t *testing.T) {
+		assert.Equal(t, jitter(testName, time.Minute), jitter(testName, time.Minute))
+	})
+
+	t.Run("should spread names across interval", func(t *testing.T) {
+		offsets := make(map[time.Duration]bool)
+		for _, name := range []string{"a", "b", "c", "d", "e"} {
+			offset := jitter(name, time.Minute)
+			assert.True(t, offset >= 0 && offset < time.Minute)
+			offsets[offset] = true
+		}
+		assert.Greater(t, len(offsets), 1)
+	})
+}
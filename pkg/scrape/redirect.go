@@ -0,0 +1,128 @@
+package scrape
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// defaultMaxRedirects is the number of redirects followed when the policy
+// does not limit it, the same as the http.Client default.
+const defaultMaxRedirects = 10
+
+// RedirectPolicy defines if and how many redirects are followed. When the
+// number of redirects exceeds MaxHops, the last redirect response is used as
+// the result of the scrape.
+type RedirectPolicy struct {
+	Follow  bool `json:"follow"`
+	MaxHops int  `json:"max_hops,omitempty"`
+}
+
+// Redirect represents a single redirect response in a redirect chain.
+type Redirect struct {
+	URL        string `json:"url"`
+	StatusCode int    `json:"status_code"`
+	Location   string `json:"location"`
+	LatencyMs  int    `json:"latency_ms"`
+}
+
+// Validate checks that the policy is well-formed.
+func (p RedirectPolicy) Validate() error {
+	if p.MaxHops < 0 {
+		return errors.Errorf("max hops %d must not be negative", p.MaxHops)
+	}
+	if p.MaxHops > 0 && !p.Follow {
+		return errors.New("max hops requires follow")
+	}
+	return nil
+}
+
+// maxHops returns the maximum number of followed redirects.
+func (p RedirectPolicy) maxHops() int {
+	if !p.Follow {
+		return 0
+	}
+	if p.MaxHops == 0 {
+		return defaultMaxRedirects
+	}
+	return p.MaxHops
+}
+
+// NewHTTPClient returns an http client with the given timeout that follows
+// redirects according to the redirect policy of the scraped target and
+// records redirect chains.
+func NewHTTPClient(timeout time.Duration) *http.Client {
+	return &http.Client{
+		Timeout:       timeout,
+		CheckRedirect: CheckRedirect,
+	}
+}
+
+// CheckRedirect implements http.Client CheckRedirect. It applies the redirect
+// policy and records the redirect chain of a scrape request. For other
+// requests it behaves like the default policy.
+func CheckRedirect(req *http.Request, via []*http.Request) error {
+	rec, ok := req.Context().Value(redirectsKey{}).(*redirectRecorder)
+	if !ok {
+		if len(via) >= defaultMaxRedirects {
+			return errors.Errorf("stopped after %d redirects", defaultMaxRedirects)
+		}
+		return nil
+	}
+
+	rec.record(via[len(via)-1], req)
+	if len(via) > rec.policy.maxHops() {
+		return http.ErrUseLastResponse
+	}
+	return nil
+}
+
+// redirectsKey is the context key of a redirect recorder.
+type redirectsKey struct{}
+
+// redirectRecorder records a redirect chain of a single scrape request.
+type redirectRecorder struct {
+	mu        sync.Mutex
+	policy    RedirectPolicy
+	last      time.Time
+	redirects []Redirect
+}
+
+// newRedirectRecorder creates a new recorder for a request started now.
+func newRedirectRecorder(policy RedirectPolicy) *redirectRecorder {
+	return &redirectRecorder{policy: policy, last: time.Now()}
+}
+
+// withRedirects returns a copy of ctx that carries the recorder.
+func withRedirects(ctx context.Context, rec *redirectRecorder) context.Context {
+	return context.WithValue(ctx, redirectsKey{}, rec)
+}
+
+// record records the redirect response of the previous request that caused
+// the next request.
+func (r *redirectRecorder) record(prev, next *http.Request) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	redirect := Redirect{
+		URL:       prev.URL.Redacted(),
+		Location:  next.URL.Redacted(),
+		LatencyMs: int(now.Sub(r.last).Milliseconds()),
+	}
+	if next.Response != nil {
+		redirect.StatusCode = next.Response.StatusCode
+	}
+	r.redirects = append(r.redirects, redirect)
+	r.last = now
+}
+
+// chain returns the recorded redirects.
+func (r *redirectRecorder) chain() []Redirect {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.redirects
+}
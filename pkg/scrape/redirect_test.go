@@ -0,0 +1,106 @@
+package scrape
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newRedirectServer returns a server that redirects /hop/N to /hop/N-1 and
+// answers OK on /hop/0.
+func newRedirectServer() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			n, _ := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/hop/"))
+			if n == 0 {
+				_, _ = w.Write([]byte("landed"))
+				return
+			}
+			http.Redirect(w, r, fmt.Sprintf("/hop/%d", n-1), http.StatusFound)
+		},
+	))
+}
+
+func TestHTTPScraper_ScrapeRedirects(t *testing.T) {
+	server := newRedirectServer()
+	defer server.Close()
+	client := NewHTTPClient(time.Second)
+
+	t.Run("should follow redirects by default", func(t *testing.T) {
+		s := newHTTPScraper(client, Target{URL: server.URL + "/hop/2"})
+
+		res, err := s.Scrape(context.Background())
+
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+		require.Len(t, res.Redirects, 2)
+		assert.Equal(t, server.URL+"/hop/2", res.Redirects[0].URL)
+		assert.Equal(t, http.StatusFound, res.Redirects[0].StatusCode)
+		assert.Equal(t, server.URL+"/hop/1", res.Redirects[0].Location)
+		assert.Equal(t, server.URL+"/hop/1", res.Redirects[1].URL)
+		assert.Equal(t, server.URL+"/hop/0", res.Redirects[1].Location)
+	})
+
+	t.Run("should not follow redirects", func(t *testing.T) {
+		s := newHTTPScraper(client, Target{
+			URL:            server.URL + "/hop/2",
+			RedirectPolicy: &RedirectPolicy{Follow: false},
+		})
+
+		res, err := s.Scrape(context.Background())
+
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusFound, res.StatusCode)
+		require.Len(t, res.Redirects, 1)
+		assert.Equal(t, server.URL+"/hop/1", res.Redirects[0].Location)
+	})
+
+	t.Run("should stop after max hops", func(t *testing.T) {
+		s := newHTTPScraper(client, Target{
+			URL:            server.URL + "/hop/5",
+			RedirectPolicy: &RedirectPolicy{Follow: true, MaxHops: 2},
+		})
+
+		res, err := s.Scrape(context.Background())
+
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusFound, res.StatusCode)
+		require.Len(t, res.Redirects, 3)
+		assert.Equal(t, server.URL+"/hop/2", res.Redirects[2].Location)
+	})
+
+	t.Run("should not record redirects when there are none", func(t *testing.T) {
+		s := newHTTPScraper(client, Target{URL: server.URL + "/hop/0"})
+
+		res, err := s.Scrape(context.Background())
+
+		require.NoError(t, err)
+		assert.Empty(t, res.Redirects)
+	})
+}
+
+func TestCheckRedirect(t *testing.T) {
+	t.Run("should stop after 10 redirects without policy", func(t *testing.T) {
+		server := newRedirectServer()
+		defer server.Close()
+
+		_, err := NewHTTPClient(time.Second).Get(server.URL + "/hop/11")
+
+		require.Error(t, err)
+		assert.Regexp(t, "stopped after 10 redirects", err)
+	})
+}
+
+func TestRedirectPolicy_Validate(t *testing.T) {
+	assert.NoError(t, RedirectPolicy{Follow: true, MaxHops: 3}.Validate())
+	assert.Error(t, RedirectPolicy{Follow: true, MaxHops: -1}.Validate())
+	assert.Error(t, RedirectPolicy{Follow: false, MaxHops: 5}.Validate())
+}
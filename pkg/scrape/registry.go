@@ -0,0 +1,160 @@
+package scrape
+
+import (
+	"bytes"
+	"encoding/json"
+	"net"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Kind is a kind of probe, e.g. http.
+type Kind string
+
+// KindHTTP is the kind of HTTPScraper, the default kind.
+const KindHTTP Kind = "http"
+
+// defaultProbeTimeout limits a scrape of a probe kind without a configured
+// timeout.
+const defaultProbeTimeout = 10 * time.Second
+
+// Factory creates a scraper for the given target. It returns an error if the
+// target, including its kind-specific settings, is invalid.
+type Factory func(target Target) (Scraper, error)
+
+// Registry maps probe kinds to scraper factories.
+// Registry is safe for concurrent use.
+type Registry struct {
+	mu        sync.RWMutex
+	factories map[Kind]Factory
+}
+
+// NewRegistry creates an empty Registry.
+func NewRegistry() *Registry {
+	return &Registry{factories: make(map[Kind]Factory)}
+}
+
+// NewDefaultRegistry creates a Registry with all the built-in probe kinds.
+// HTTP scrapers use the given client.
+func NewDefaultRegistry(client httpClient) *Registry {
+	r := NewRegistry()
+	_ = r.Register(KindHTTP, httpFactory(client))
+	_ = r.Register(KindTCP, tcpFactory(&net.Dialer{}))
+	_ = r.Register(KindDNS, dnsFactory)
+	_ = r.Register(KindGRPC, grpcFactory)
+	_ = r.Register(KindWebSocket, webSocketFactory)
+	_ = r.Register(KindTransaction, transactionFactory(client))
+	return r
+}
+
+// Register registers the factory for the given kind. It returns an error if
+// the kind is already registered.
+func (r *Registry) Register(kind Kind, factory Factory) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if kind == "" {
+		return errors.New("probe kind must not be empty")
+	}
+	if _, exists := r.factories[kind]; exists {
+		return errors.Errorf("probe kind %s is already registered", kind)
+	}
+	r.factories[kind] = factory
+	return nil
+}
+
+// Kinds returns the registered kinds in alphabetical order.
+func (r *Registry) Kinds() []Kind {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	kinds := make([]Kind, 0, len(r.factories))
+	for kind := range r.factories {
+		kinds = append(kinds, kind)
+	}
+	sort.Slice(kinds, func(i, j int) bool { return kinds[i] < kinds[j] })
+	return kinds
+}
+
+// New creates a scraper for the given target with the factory of the target
+// kind. An empty kind means http. The scraper retries failed scrapes if the
+// target has a retry policy, the failing policy is validated.
+func (r *Registry) New(target Target) (Scraper, error) {
+	kind := target.kind()
+
+	r.mu.RLock()
+	factory, exists := r.factories[kind]
+	r.mu.RUnlock()
+
+	if !exists {
+		return nil, errors.Errorf(
+			"unknown probe kind %q, supported kinds: %s", kind, r.Kinds(),
+		)
+	}
+
+	s, err := factory(target)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid %s target", kind)
+	}
+
+	if target.Retry != nil {
+		if err = target.Retry.Validate(); err != nil {
+			return nil, errors.Wrapf(err, "invalid %s target", kind)
+		}
+		s = newRetryScraper(s, *target.Retry)
+	}
+
+	if target.Failing != nil {
+		if err = target.Failing.Validate(); err != nil {
+			return nil, errors.Wrapf(err, "invalid %s target", kind)
+		}
+	}
+	return s, nil
+}
+
+// httpFactory returns a factory of HTTPScrapers that use the given client.
+func httpFactory(client httpClient) Factory {
+	return func(target Target) (Scraper, error) {
+		if err := target.Validate(); err != nil {
+			return nil, err
+		}
+		target.Assertions = compileAssertions(target.Assertions)
+		s := newHTTPScraper(client, target)
+		// the script is valid, it has been compiled by the validation.
+		s.script, _ = compileScript(target.Script)
+		return s, nil
+	}
+}
+
+// decodeSettings decodes the given kind-specific settings into v. Empty
+// settings leave v unchanged, unknown fields are rejected.
+func decodeSettings(settings json.RawMessage, v interface{}) error {
+	if len(settings) == 0 || string(settings) == "null" {
+		return nil
+	}
+	d := json.NewDecoder(bytes.NewReader(settings))
+	d.DisallowUnknownFields()
+	if err := d.Decode(v); err != nil {
+		return errors.Wrap(err, "failed to decode settings")
+	}
+	return nil
+}
+
+// parseTimeout parses the given probe timeout, an empty timeout means the
+// default one. Only positive timeouts are valid.
+func parseTimeout(timeout string) (time.Duration, error) {
+	if timeout == "" {
+		return defaultProbeTimeout, nil
+	}
+	d, err := time.ParseDuration(timeout)
+	if err != nil {
+		return 0, errors.Wrapf(err, "failed to parse timeout %q", timeout)
+	}
+	if d <= 0 {
+		return 0, errors.Errorf("timeout %q must be positive", timeout)
+	}
+	return d, nil
+}
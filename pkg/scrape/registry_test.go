@@ -0,0 +1,83 @@
+package scrape
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegistry_Register(t *testing.T) {
+	factory := func(Target) (Scraper, error) { return &scraperMock{}, nil }
+
+	t.Run("should return error on empty kind", func(t *testing.T) {
+		r := NewRegistry()
+
+		err := r.Register("", factory)
+
+		assert.Error(t, err)
+	})
+
+	t.Run("should return error on duplicate kind", func(t *testing.T) {
+		r := NewRegistry()
+		require.NoError(t, r.Register("test", factory))
+
+		err := r.Register("test", factory)
+
+		require.Error(t, err)
+		assert.Regexp(t, "test", err)
+	})
+
+	t.Run("should list registered kinds", func(t *testing.T) {
+		r := NewDefaultRegistry(newOKClient())
+		require.NoError(t, r.Register("a", factory))
+
+		assert.Equal(t, []Kind{"a", KindDNS, KindGRPC, KindHTTP, KindTCP, KindTransaction, KindWebSocket}, r.Kinds())
+	})
+}
+
+func TestRegistry_New(t *testing.T) {
+	t.Run("should return error on unknown kind", func(t *testing.T) {
+		r := NewDefaultRegistry(newOKClient())
+
+		_, err := r.New(Target{Kind: "unknown", URL: "https://example.com"})
+
+		require.Error(t, err)
+		assert.Regexp(t, `unknown probe kind "unknown", supported kinds: \[dns grpc http`, err)
+	})
+
+	t.Run("should create http scraper by default", func(t *testing.T) {
+		r := NewDefaultRegistry(newOKClient())
+
+		s, err := r.New(testTarget)
+
+		require.NoError(t, err)
+		assert.IsType(t, &HTTPScraper{}, s)
+	})
+
+	t.Run("should return error on invalid target", func(t *testing.T) {
+		r := NewDefaultRegistry(newOKClient())
+		target := testTarget
+		target.Method = "BREW"
+
+		_, err := r.New(target)
+
+		require.Error(t, err)
+		assert.Regexp(t, "http", err)
+	})
+
+	t.Run("should pass target to factory", func(t *testing.T) {
+		r := NewRegistry()
+		var got Target
+		require.NoError(t, r.Register("test", func(target Target) (Scraper, error) {
+			got = target
+			return &scraperMock{}, nil
+		}))
+		target := Target{Kind: "test", URL: "example.com:80", Settings: []byte(`{"a":1}`)}
+
+		_, err := r.New(target)
+
+		require.NoError(t, err)
+		assert.Equal(t, target, got)
+	})
+}
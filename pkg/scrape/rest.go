@@ -0,0 +1,39 @@
+package scrape
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+)
+
+type statsProvider interface {
+	Stats() PipelineStats
+}
+
+// Handler represents a scrape handler.
+type Handler struct {
+	stats statsProvider
+}
+
+// NewHandler creates a new scrape handler.
+func NewHandler(stats statsProvider) *Handler {
+	return &Handler{stats: stats}
+}
+
+// GetStats returns the counters of scrape results passed to the consumers.
+// GET /scrapers/stats.
+func (h *Handler) GetStats(w http.ResponseWriter, _ *http.Request) {
+	output, err := json.Marshal(h.stats.Stats())
+	if err != nil {
+		log.Printf("failed to marshal response %+v\n", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("content-type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_, err = w.Write(output)
+	if err != nil {
+		log.Printf("failed to write response: %+v\n", err)
+	}
+}
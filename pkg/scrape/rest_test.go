@@ -0,0 +1,36 @@
+package scrape
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi"
+	"github.com/stretchr/testify/assert"
+)
+
+const statsPath = "/scrapers/stats"
+
+type statsMock PipelineStats
+
+func (s statsMock) Stats() PipelineStats {
+	return PipelineStats(s)
+}
+
+func TestHandler_GetStats(t *testing.T) {
+	t.Run("should return pipeline stats", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodGet, statsPath, nil)
+		w := httptest.NewRecorder()
+		router := chi.NewRouter()
+		handler := NewHandler(statsMock{Published: 5, Dropped: 2, Delayed: 1, DelayMs: 40})
+		router.Get(statsPath, handler.GetStats)
+
+		router.ServeHTTP(w, r)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.JSONEq(t,
+			`{"published":5,"dropped":2,"delayed":1,"delay_ms":40}`,
+			w.Body.String(),
+		)
+	})
+}
@@ -0,0 +1,207 @@
+package scrape
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// maxRetryAttempts limits the number of attempts of a scrape.
+	maxRetryAttempts = 10
+	// defaultRetryBackoff is the wait before the first retry when the policy
+	// does not define it.
+	defaultRetryBackoff = time.Second
+	// defaultMaxRetryBackoff limits the wait between retries when the policy
+	// does not limit it.
+	defaultMaxRetryBackoff = 10 * time.Second
+)
+
+// defaultRetryErrorKinds are the error kinds retried when the policy does not
+// define them.
+var defaultRetryErrorKinds = []ErrorKind{
+	ErrorKindConnect, ErrorKindTimeout, ErrorKindRead,
+}
+
+// RetryPolicy defines how a failed scrape is retried before it is declared
+// failed. Attempts is the maximum number of attempts including the first
+// one. The wait between attempts starts with Backoff, 1s if empty, and
+// doubles up to MaxBackoff, 10s if empty. Scrapes failed with one of
+// ErrorKinds, connect, timeout and read if empty, and responses with one of
+// StatusCodes are retried.
+type RetryPolicy struct {
+	Attempts    int         `json:"attempts"`
+	Backoff     string      `json:"backoff,omitempty"`
+	MaxBackoff  string      `json:"max_backoff,omitempty"`
+	ErrorKinds  []ErrorKind `json:"error_kinds,omitempty"`
+	StatusCodes []int       `json:"status_codes,omitempty"`
+}
+
+// Attempt represents a single attempt of a retried scrape.
+type Attempt struct {
+	ResponseTimeMs int       `json:"response_time_ms"`
+	Outcome        Outcome   `json:"outcome"`
+	ErrorKind      ErrorKind `json:"error_kind,omitempty"`
+	StatusCode     int       `json:"status_code,omitempty"`
+}
+
+// Validate checks that the policy is well-formed.
+func (p RetryPolicy) Validate() error {
+	if p.Attempts < 1 || p.Attempts > maxRetryAttempts {
+		return errors.Errorf(
+			"retry attempts %d must be between 1 and %d",
+			p.Attempts, maxRetryAttempts,
+		)
+	}
+	if _, _, err := p.backoffs(); err != nil {
+		return err
+	}
+	for _, code := range p.StatusCodes {
+		if code < 100 || code > 599 {
+			return errors.Errorf("invalid retry status code %d", code)
+		}
+	}
+	return nil
+}
+
+// backoffs returns the initial and the maximum waits between attempts.
+func (p RetryPolicy) backoffs() (time.Duration, time.Duration, error) {
+	backoff, maxBackoff := defaultRetryBackoff, defaultMaxRetryBackoff
+	var err error
+	if p.Backoff != "" {
+		if backoff, err = time.ParseDuration(p.Backoff); err != nil || backoff < 0 {
+			return 0, 0, errors.Errorf("invalid retry backoff %q", p.Backoff)
+		}
+	}
+	if p.MaxBackoff != "" {
+		if maxBackoff, err = time.ParseDuration(p.MaxBackoff); err != nil || maxBackoff < 0 {
+			return 0, 0, errors.Errorf("invalid retry max backoff %q", p.MaxBackoff)
+		}
+	}
+	if maxBackoff < backoff {
+		maxBackoff = backoff
+	}
+	return backoff, maxBackoff, nil
+}
+
+// retryable reports whether a scrape with the given result and error should
+// be retried. Throttled scrapes are never retried.
+func (p RetryPolicy) retryable(res Result, err error) bool {
+	if res.Throttled {
+		return false
+	}
+
+	var kind ErrorKind
+	switch {
+	case err != nil:
+		kind = kindOf(err)
+	case res.Outcome == OutcomeFailure:
+		kind = res.ErrorKind
+	}
+
+	if kind != "" {
+		kinds := p.ErrorKinds
+		if len(kinds) == 0 {
+			kinds = defaultRetryErrorKinds
+		}
+		for _, k := range kinds {
+			if k == kind {
+				return true
+			}
+		}
+	}
+
+	if err == nil {
+		for _, code := range p.StatusCodes {
+			if code == res.StatusCode {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// retryScraper retries the scrapes of the wrapped scraper according to the
+// retry policy.
+type retryScraper struct {
+	scraper Scraper
+	policy  RetryPolicy
+	sleep   func(ctx context.Context, d time.Duration) error
+}
+
+// newRetryScraper wraps the given scraper with retries. The policy is
+// expected to be valid.
+func newRetryScraper(scraper Scraper, policy RetryPolicy) *retryScraper {
+	return &retryScraper{
+		scraper: scraper,
+		policy:  policy,
+		sleep:   sleep,
+	}
+}
+
+// sleep waits for the given duration unless the context is done first.
+func sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Scrape scrapes until the scrape is not retryable, the attempts are
+// exhausted or the context is done and returns the last result. The result
+// records all attempts, the error of the last failed attempt is returned as
+// is.
+func (s *retryScraper) Scrape(ctx context.Context) (Result, error) {
+	backoff, maxBackoff, _ := s.policy.backoffs()
+
+	var attempts []Attempt
+	for i := 1; ; i++ {
+		start := time.Now()
+		res, err := s.scraper.Scrape(ctx)
+		attempt := Attempt{
+			ResponseTimeMs: int(time.Since(start).Milliseconds()),
+			Outcome:        res.Outcome,
+			ErrorKind:      res.ErrorKind,
+			StatusCode:     res.StatusCode,
+		}
+		if err != nil {
+			attempt.Outcome = OutcomeFailure
+			attempt.ErrorKind = kindOf(err)
+		}
+		attempts = append(attempts, attempt)
+
+		last := i >= s.policy.Attempts || !s.policy.retryable(res, err)
+		if last || s.sleep(ctx, backoff) != nil {
+			if err != nil {
+				return Result{}, &attemptsError{err: err, attempts: attempts}
+			}
+			res.Attempts = attempts
+			return res, nil
+		}
+		if backoff *= 2; backoff > maxBackoff {
+			backoff = maxBackoff
+		}
+	}
+}
+
+// attemptsError is an error of the last attempt of a retried scrape, it keeps
+// the attempts so that they are published with the failed result.
+type attemptsError struct {
+	err      error
+	attempts []Attempt
+}
+
+// Error returns the message of the last attempt error.
+func (e *attemptsError) Error() string {
+	return e.err.Error()
+}
+
+// Unwrap returns the last attempt error.
+func (e *attemptsError) Unwrap() error {
+	return e.err
+}
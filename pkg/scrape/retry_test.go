@@ -0,0 +1,221 @@
+package scrape
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRetryPolicy_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  RetryPolicy
+		wantErr string
+	}{
+		{
+			name:    "should return error on no attempts",
+			policy:  RetryPolicy{},
+			wantErr: "must be between",
+		},
+		{
+			name:    "should return error on too many attempts",
+			policy:  RetryPolicy{Attempts: maxRetryAttempts + 1},
+			wantErr: "must be between",
+		},
+		{
+			name:    "should return error on invalid backoff",
+			policy:  RetryPolicy{Attempts: 3, Backoff: "soon"},
+			wantErr: "invalid retry backoff",
+		},
+		{
+			name:    "should return error on negative max backoff",
+			policy:  RetryPolicy{Attempts: 3, MaxBackoff: "-1s"},
+			wantErr: "invalid retry max backoff",
+		},
+		{
+			name:    "should return error on invalid status code",
+			policy:  RetryPolicy{Attempts: 3, StatusCodes: []int{42}},
+			wantErr: "invalid retry status code",
+		},
+		{
+			name: "should accept valid policy",
+			policy: RetryPolicy{
+				Attempts:    3,
+				Backoff:     "100ms",
+				MaxBackoff:  "1s",
+				ErrorKinds:  []ErrorKind{ErrorKindDNS},
+				StatusCodes: []int{http.StatusServiceUnavailable},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.policy.Validate()
+
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Regexp(t, tt.wantErr, err)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestRetryScraper_Scrape(t *testing.T) {
+	// newScraper returns a retry scraper of the given results and the waits
+	// between the attempts.
+	newScraper := func(
+		policy RetryPolicy, results ...func() (Result, error),
+	) (*retryScraper, *[]time.Duration) {
+		i := 0
+		s := newRetryScraper(&scraperMock{
+			scrapeMock: func() (Result, error) {
+				r := results[i]
+				i++
+				return r()
+			},
+		}, policy)
+		var waits []time.Duration
+		s.sleep = func(_ context.Context, d time.Duration) error {
+			waits = append(waits, d)
+			return nil
+		}
+		return s, &waits
+	}
+	ok := func() (Result, error) {
+		return Result{Outcome: OutcomeSuccess, StatusCode: http.StatusOK}, nil
+	}
+	unavailable := func() (Result, error) {
+		return Result{Outcome: OutcomeSuccess, StatusCode: http.StatusServiceUnavailable}, nil
+	}
+	timeout := func() (Result, error) {
+		return Result{}, newError(ErrorKindTimeout, assert.AnError)
+	}
+	dns := func() (Result, error) {
+		return Result{}, newError(ErrorKindDNS, assert.AnError)
+	}
+
+	t.Run("should record single successful attempt", func(t *testing.T) {
+		s, waits := newScraper(RetryPolicy{Attempts: 3}, ok)
+
+		res, err := s.Scrape(context.Background())
+
+		require.NoError(t, err)
+		assert.Equal(t, OutcomeSuccess, res.Outcome)
+		require.Len(t, res.Attempts, 1)
+		assert.Equal(t, http.StatusOK, res.Attempts[0].StatusCode)
+		assert.Empty(t, *waits)
+	})
+
+	t.Run("should retry default error kinds with backoff", func(t *testing.T) {
+		s, waits := newScraper(
+			RetryPolicy{Attempts: 4, Backoff: "1s", MaxBackoff: "3s"},
+			timeout, timeout, timeout, ok,
+		)
+
+		res, err := s.Scrape(context.Background())
+
+		require.NoError(t, err)
+		assert.Equal(t, OutcomeSuccess, res.Outcome)
+		require.Len(t, res.Attempts, 4)
+		for _, a := range res.Attempts[:3] {
+			assert.Equal(t, OutcomeFailure, a.Outcome)
+			assert.Equal(t, ErrorKindTimeout, a.ErrorKind)
+		}
+		assert.Equal(t, OutcomeSuccess, res.Attempts[3].Outcome)
+		assert.Equal(t, []time.Duration{time.Second, 2 * time.Second, 3 * time.Second}, *waits)
+	})
+
+	t.Run("should not retry other error kinds", func(t *testing.T) {
+		s, _ := newScraper(RetryPolicy{Attempts: 3}, dns, ok)
+
+		_, err := s.Scrape(context.Background())
+
+		require.Error(t, err)
+		res := failedResult(err)
+		assert.Equal(t, ErrorKindDNS, res.ErrorKind)
+		assert.Len(t, res.Attempts, 1)
+	})
+
+	t.Run("should retry configured error kinds", func(t *testing.T) {
+		s, _ := newScraper(
+			RetryPolicy{Attempts: 3, ErrorKinds: []ErrorKind{ErrorKindDNS}},
+			dns, ok,
+		)
+
+		res, err := s.Scrape(context.Background())
+
+		require.NoError(t, err)
+		assert.Len(t, res.Attempts, 2)
+	})
+
+	t.Run("should retry configured status codes", func(t *testing.T) {
+		s, _ := newScraper(
+			RetryPolicy{Attempts: 3, StatusCodes: []int{http.StatusServiceUnavailable}},
+			unavailable, unavailable, ok,
+		)
+
+		res, err := s.Scrape(context.Background())
+
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+		assert.Len(t, res.Attempts, 3)
+	})
+
+	t.Run("should return last failure when attempts are exhausted", func(t *testing.T) {
+		s, waits := newScraper(RetryPolicy{Attempts: 2}, timeout, timeout)
+
+		_, err := s.Scrape(context.Background())
+
+		require.Error(t, err)
+		res := failedResult(err)
+		assert.Equal(t, OutcomeFailure, res.Outcome)
+		assert.Equal(t, ErrorKindTimeout, res.ErrorKind)
+		assert.Len(t, res.Attempts, 2)
+		assert.Len(t, *waits, 1)
+	})
+
+	t.Run("should stop retrying when context is done", func(t *testing.T) {
+		s, _ := newScraper(RetryPolicy{Attempts: 3, Backoff: "1h"}, timeout, timeout, timeout)
+		s.sleep = sleep
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		_, err := s.Scrape(ctx)
+
+		require.Error(t, err)
+		assert.Len(t, failedResult(err).Attempts, 1)
+	})
+}
+
+func TestRegistry_NewWithRetry(t *testing.T) {
+	r := NewDefaultRegistry(newOKClient())
+
+	t.Run("should return error on invalid retry policy", func(t *testing.T) {
+		target := testTarget
+		target.Retry = &RetryPolicy{}
+
+		_, err := r.New(target)
+
+		require.Error(t, err)
+		assert.Regexp(t, "retry attempts", err)
+	})
+
+	t.Run("should wrap scraper with retries", func(t *testing.T) {
+		target := testTarget
+		target.Retry = &RetryPolicy{Attempts: 2}
+
+		s, err := r.New(target)
+
+		require.NoError(t, err)
+		res, err := s.Scrape(context.Background())
+		require.NoError(t, err)
+		assert.Len(t, res.Attempts, 1)
+	})
+}
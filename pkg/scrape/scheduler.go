@@ -0,0 +1,346 @@
+package scrape
+
+import (
+	"container/heap"
+	"context"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Scheduler runs the scrapes of producers when they are due. Due producers
+// are dispatched to a fixed pool of workers, optionally with a limited number
+// of concurrent scrapes per host. Results are passed to the consumers through
+// the pipeline. Scheduler is safe for concurrent use.
+type Scheduler struct {
+	mu         sync.Mutex
+	queue      producerQueue
+	hosts      map[string]int
+	blocked    map[string][]*producer
+	maxPerHost int
+	pipeline   Pipeline
+	counters   pipelineCounters
+	// ctx is canceled when running scrapes are aborted.
+	ctx    context.Context
+	cancel context.CancelFunc
+	// closed is set when the scheduler is shut down.
+	closed bool
+
+	wakeCh    chan struct{}
+	jobs      chan *producer
+	closeOnce sync.Once
+	closeCh   chan struct{}
+	wg        sync.WaitGroup
+}
+
+// NewScheduler creates a new Scheduler and starts the given number of
+// workers, at least one. Zero maxPerHost means the number of concurrent
+// scrapes per host is not limited. Running scrapes are aborted when the given
+// context is done or the scheduler is closed.
+func NewScheduler(
+	ctx context.Context, workers, maxPerHost int, pipeline Pipeline,
+) (*Scheduler, error) {
+	if err := pipeline.Validate(); err != nil {
+		return nil, err
+	}
+	if workers < 1 {
+		workers = 1
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	s := &Scheduler{
+		ctx:        ctx,
+		cancel:     cancel,
+		hosts:      make(map[string]int),
+		blocked:    make(map[string][]*producer),
+		maxPerHost: maxPerHost,
+		pipeline:   pipeline,
+		wakeCh:     make(chan struct{}, 1),
+		jobs:       make(chan *producer),
+		closeCh:    make(chan struct{}),
+	}
+
+	s.wg.Add(workers + 1)
+	go s.dispatch()
+	for i := 0; i < workers; i++ {
+		go s.work()
+	}
+	return s, nil
+}
+
+// Stats returns the counters of the results passed to the consumers.
+func (s *Scheduler) Stats() PipelineStats {
+	return s.counters.stats()
+}
+
+// newProducer constructs a new producer with the result buffer of the
+// pipeline.
+func (s *Scheduler) newProducer(
+	name string, scraper Scraper, target Target,
+) *producer {
+	return newProducer(s.ctx, name, scraper, target, s.pipeline.Capacity)
+}
+
+// Shutdown stops dispatching scrapes and waits for the running scrapes to
+// finish and publish their results. If the context is done first, the running
+// scrapes are aborted and an error is returned. The result channels of all
+// producers are closed, producers scheduled afterwards are stopped at once.
+func (s *Scheduler) Shutdown(ctx context.Context) error {
+	s.closeOnce.Do(func() {
+		close(s.closeCh)
+	})
+
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+
+	var err error
+	select {
+	case <-done:
+	case <-ctx.Done():
+		err = errors.Wrap(ctx.Err(), "running scrapes are aborted")
+		s.cancel()
+		<-done
+	}
+	s.cancel()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+	for s.queue.Len() > 0 {
+		s.stopProducer(heap.Pop(&s.queue).(*producer))
+	}
+	for host, blocked := range s.blocked {
+		for _, p := range blocked {
+			p.blocked = false
+			s.stopProducer(p)
+		}
+		delete(s.blocked, host)
+	}
+	return err
+}
+
+// schedule schedules the first scrape of the producer at the given time.
+func (s *Scheduler) schedule(p *producer, due time.Time) {
+	s.mu.Lock()
+	if s.closed {
+		s.stopProducer(p)
+		s.mu.Unlock()
+		return
+	}
+	p.due = due
+	heap.Push(&s.queue, p)
+	s.mu.Unlock()
+	s.wake()
+}
+
+// remove stops the producer and removes it from the scheduler. The result
+// channel of the producer is closed immediately if it is not being scraped,
+// otherwise after the running scrape.
+func (s *Scheduler) remove(p *producer) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if p.stopped {
+		return
+	}
+	p.stop()
+	p.stopped = true
+	switch {
+	case p.index >= 0:
+		heap.Remove(&s.queue, p.index)
+	case p.blocked:
+		s.unblock(p)
+	}
+	if !p.running {
+		close(p.resCh)
+	}
+}
+
+// stopProducer stops the producer which is neither scheduled nor running and
+// closes its result channel. The caller must hold the lock.
+func (s *Scheduler) stopProducer(p *producer) {
+	p.stop()
+	p.stopped = true
+	close(p.resCh)
+}
+
+// dispatch sends due producers to the workers until the scheduler is shut
+// down.
+func (s *Scheduler) dispatch() {
+	defer s.wg.Done()
+
+	t := time.NewTimer(time.Hour)
+	defer t.Stop()
+	for {
+		s.mu.Lock()
+		p, wait := s.next(time.Now())
+		s.mu.Unlock()
+
+		if p != nil {
+			select {
+			case s.jobs <- p:
+			case <-s.closeCh:
+				// the producer is not scraped, it is stopped by the shutdown.
+				s.done(p, p.due)
+				return
+			}
+			continue
+		}
+
+		if !t.Stop() {
+			select {
+			case <-t.C:
+			default:
+			}
+		}
+		t.Reset(wait)
+		select {
+		case <-t.C:
+		case <-s.wakeCh:
+		case <-s.closeCh:
+			return
+		}
+	}
+}
+
+// next pops the first due producer whose host is not at the concurrency limit
+// and marks it as running. If there is none, it returns the wait until the
+// next producer is due. The caller must hold the lock.
+func (s *Scheduler) next(now time.Time) (*producer, time.Duration) {
+	for s.queue.Len() > 0 {
+		p := s.queue[0]
+		if wait := p.due.Sub(now); wait > 0 {
+			return nil, wait
+		}
+		heap.Pop(&s.queue)
+
+		if s.maxPerHost > 0 && s.hosts[p.host] >= s.maxPerHost {
+			p.blocked = true
+			s.blocked[p.host] = append(s.blocked[p.host], p)
+			continue
+		}
+		s.hosts[p.host]++
+		p.running = true
+		return p, 0
+	}
+	return nil, time.Hour
+}
+
+// work scrapes the dispatched producers until the scheduler is shut down.
+func (s *Scheduler) work() {
+	defer s.wg.Done()
+
+	for {
+		select {
+		case p := <-s.jobs:
+			res, due := p.scrape()
+			s.counters.publish(s.pipeline.overflow(), p, res, s.ctx.Done())
+			s.done(p, due)
+		case <-s.closeCh:
+			return
+		}
+	}
+}
+
+// done reschedules the producer after a scrape at the given time and releases
+// producers blocked on its host. The result channel of a stopped producer is
+// closed.
+func (s *Scheduler) done(p *producer, due time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	p.running = false
+	s.hosts[p.host]--
+	if s.hosts[p.host] == 0 {
+		delete(s.hosts, p.host)
+	}
+	if blocked := s.blocked[p.host]; len(blocked) > 0 {
+		delete(s.blocked, p.host)
+		for _, b := range blocked {
+			b.blocked = false
+			heap.Push(&s.queue, b)
+		}
+	}
+
+	if p.stopped {
+		close(p.resCh)
+	} else {
+		p.due = due
+		heap.Push(&s.queue, p)
+	}
+	s.wake()
+}
+
+// unblock removes the producer from the producers blocked on its host. The
+// caller must hold the lock.
+func (s *Scheduler) unblock(p *producer) {
+	blocked := s.blocked[p.host]
+	for i, b := range blocked {
+		if b == p {
+			blocked = append(blocked[:i], blocked[i+1:]...)
+			break
+		}
+	}
+	if len(blocked) == 0 {
+		delete(s.blocked, p.host)
+	} else {
+		s.blocked[p.host] = blocked
+	}
+	p.blocked = false
+}
+
+// wake wakes up the dispatcher to recheck the queue.
+func (s *Scheduler) wake() {
+	select {
+	case s.wakeCh <- struct{}{}:
+	default:
+	}
+}
+
+// producerQueue is a heap of producers ordered by the time of the next
+// scrape.
+type producerQueue []*producer
+
+func (q producerQueue) Len() int { return len(q) }
+
+func (q producerQueue) Less(i, j int) bool { return q[i].due.Before(q[j].due) }
+
+func (q producerQueue) Swap(i, j int) {
+	q[i], q[j] = q[j], q[i]
+	q[i].index = i
+	q[j].index = j
+}
+
+func (q *producerQueue) Push(x interface{}) {
+	p := x.(*producer)
+	p.index = len(*q)
+	*q = append(*q, p)
+}
+
+func (q *producerQueue) Pop() interface{} {
+	old := *q
+	p := old[len(old)-1]
+	old[len(old)-1] = nil
+	p.index = -1
+	*q = old[:len(old)-1]
+	return p
+}
+
+// targetHost returns the host the concurrency of scrapes of the target is
+// limited by: the host of a URL, the host of a host:port address, or the
+// target URL itself.
+func targetHost(target Target) string {
+	if u, err := url.Parse(target.URL); err == nil && u.Host != "" {
+		return strings.ToLower(u.Hostname())
+	}
+	if host, _, err := net.SplitHostPort(target.URL); err == nil {
+		return strings.ToLower(host)
+	}
+	return strings.ToLower(target.URL)
+}
@@ -0,0 +1,281 @@
+package scrape
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// concurrencyMock is a scraper that records the maximum number of concurrent
+// scrapes.
+type concurrencyMock struct {
+	mu      sync.Mutex
+	running int
+	max     int
+}
+
+func (c *concurrencyMock) Scrape(context.Context) (Result, error) {
+	c.mu.Lock()
+	c.running++
+	if c.running > c.max {
+		c.max = c.running
+	}
+	c.mu.Unlock()
+
+	time.Sleep(10 * time.Millisecond)
+
+	c.mu.Lock()
+	c.running--
+	c.mu.Unlock()
+	return Result{Outcome: OutcomeSuccess}, nil
+}
+
+func (c *concurrencyMock) maxRunning() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.max
+}
+
+func TestScheduler(t *testing.T) {
+	t.Run("should scrape producers in due order", func(t *testing.T) {
+		s := newTestScheduler(t, 1, 0)
+		defer abort(s)
+		var mu sync.Mutex
+		var order []string
+		newRecorder := func(name string) *producer {
+			return newProducer(context.Background(), name, &scraperMock{
+				scrapeMock: func() (Result, error) {
+					mu.Lock()
+					order = append(order, name)
+					mu.Unlock()
+					return Result{}, nil
+				},
+			}, Target{Interval: time.Hour}, 0)
+		}
+		now := time.Now()
+		late, early := newRecorder("late"), newRecorder("early")
+
+		s.schedule(late, now.Add(20*time.Millisecond))
+		s.schedule(early, now.Add(10*time.Millisecond))
+		<-early.resCh
+		<-late.resCh
+
+		assert.Equal(t, []string{"early", "late"}, order)
+		s.remove(early)
+		s.remove(late)
+	})
+
+	t.Run("should limit concurrent scrapes by workers", func(t *testing.T) {
+		s := newTestScheduler(t, 2, 0)
+		defer abort(s)
+		scraper := &concurrencyMock{}
+
+		producers := scheduleAll(s, scraper, func(i int) string {
+			return fmt.Sprintf("https://host%d.example.com", i)
+		})
+
+		assert.Equal(t, 2, scraper.maxRunning())
+		removeAll(s, producers)
+	})
+
+	t.Run("should limit concurrent scrapes per host", func(t *testing.T) {
+		s := newTestScheduler(t, 4, 1)
+		defer abort(s)
+		scraper := &concurrencyMock{}
+
+		producers := scheduleAll(s, scraper, func(i int) string {
+			return fmt.Sprintf("https://example.com/page%d", i)
+		})
+
+		assert.Equal(t, 1, scraper.maxRunning())
+		removeAll(s, producers)
+	})
+
+	t.Run("should close results of removed producer", func(t *testing.T) {
+		s := newTestScheduler(t, 1, 0)
+		defer abort(s)
+		p := newProducer(context.Background(), testName, &scraperMock{
+			scrapeMock: func() (Result, error) { return Result{}, nil },
+		}, testTarget, 0)
+		s.schedule(p, time.Now())
+		<-p.resCh
+
+		s.remove(p)
+
+		requireStopped(t, s, p)
+		assertClosed(t, p.resCh)
+	})
+
+	t.Run("should close results of producer removed while scraping", func(t *testing.T) {
+		s := newTestScheduler(t, 1, 0)
+		defer abort(s)
+		started := make(chan struct{})
+		release := make(chan struct{})
+		p := newProducer(context.Background(), testName, &scraperMock{
+			scrapeMock: func() (Result, error) {
+				close(started)
+				<-release
+				return Result{}, nil
+			},
+		}, Target{Interval: time.Hour}, 0)
+		s.schedule(p, time.Now())
+		<-started
+
+		s.remove(p)
+		close(release)
+
+		assertClosed(t, p.resCh)
+	})
+
+	t.Run("should not block aborted shutdown on unconsumed results", func(t *testing.T) {
+		s := newTestScheduler(t, 1, 0)
+		p := newProducer(context.Background(), testName, &scraperMock{
+			scrapeMock: func() (Result, error) { return Result{}, nil },
+		}, testTarget, 0)
+		s.schedule(p, time.Now())
+		time.Sleep(10 * time.Millisecond)
+
+		done := make(chan struct{})
+		go func() {
+			abort(s)
+			close(done)
+		}()
+
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Fatal("shutdown is blocked")
+		}
+	})
+}
+
+func TestScheduler_Shutdown(t *testing.T) {
+	t.Run("should finish running scrape and close results", func(t *testing.T) {
+		s := newTestScheduler(t, 1, 0)
+		started := make(chan struct{})
+		release := make(chan struct{})
+		running := s.newProducer("running", &scraperMock{
+			scrapeMock: func() (Result, error) {
+				close(started)
+				<-release
+				return Result{StatusCode: http.StatusOK}, nil
+			},
+		}, Target{Interval: time.Hour})
+		queued := s.newProducer("queued", &scraperMock{}, Target{Interval: time.Hour})
+		s.schedule(running, time.Now())
+		s.schedule(queued, time.Now().Add(time.Hour))
+		<-started
+
+		errCh := make(chan error)
+		go func() { errCh <- s.Shutdown(context.Background()) }()
+		close(release)
+
+		res := <-running.resCh
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+		assert.NoError(t, <-errCh)
+		assertClosed(t, running.resCh)
+		assertClosed(t, queued.resCh)
+	})
+
+	t.Run("should abort running scrape when context is done", func(t *testing.T) {
+		s := newTestScheduler(t, 1, 0)
+		started := make(chan struct{})
+		p := s.newProducer(testName, &ctxScraperMock{started: started}, testTarget)
+		s.schedule(p, time.Now())
+		<-started
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+
+		err := s.Shutdown(ctx)
+
+		require.Error(t, err)
+		assert.Regexp(t, "aborted", err)
+		assertClosed(t, p.resCh)
+	})
+
+	t.Run("should stop producer scheduled after shutdown", func(t *testing.T) {
+		s := newTestScheduler(t, 1, 0)
+		require.NoError(t, s.Shutdown(context.Background()))
+		p := s.newProducer(testName, &scraperMock{}, testTarget)
+
+		s.schedule(p, time.Now())
+		s.remove(p)
+
+		assertClosed(t, p.resCh)
+	})
+}
+
+func TestTargetHost(t *testing.T) {
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{url: "https://Example.com:8443/path", want: "example.com"},
+		{url: "example.com:443", want: "example.com"},
+		{url: "127.0.0.1:6379", want: "127.0.0.1"},
+		{url: "example.com", want: "example.com"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			assert.Equal(t, tt.want, targetHost(Target{URL: tt.url}))
+		})
+	}
+}
+
+// scheduleAll schedules producers of the given scraper with URLs returned by
+// urlFn, all due now, and waits for their first results.
+func scheduleAll(
+	s *Scheduler, scraper Scraper, urlFn func(i int) string,
+) []*producer {
+	const count = 6
+	producers := make([]*producer, count)
+	for i := range producers {
+		producers[i] = newProducer(
+			context.Background(),
+			fmt.Sprintf("%s_%d", testName, i),
+			scraper,
+			Target{URL: urlFn(i), Interval: time.Hour},
+			0,
+		)
+		s.schedule(producers[i], time.Now())
+	}
+	for _, p := range producers {
+		<-p.resCh
+	}
+	return producers
+}
+
+// newTestScheduler returns a new scheduler without a result buffer.
+func newTestScheduler(t *testing.T, workers, maxPerHost int) *Scheduler {
+	s, err := NewScheduler(context.Background(), workers, maxPerHost, Pipeline{})
+	require.NoError(t, err)
+	return s
+}
+
+// abort aborts the running scrapes and shuts the scheduler down.
+func abort(s *Scheduler) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_ = s.Shutdown(ctx)
+}
+
+func removeAll(s *Scheduler, producers []*producer) {
+	for _, p := range producers {
+		s.remove(p)
+	}
+}
+
+// requireStopped fails the test if the producer is still scheduled.
+func requireStopped(t *testing.T, s *Scheduler, p *producer) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	require.True(t, p.stopped)
+	require.Equal(t, -1, p.index)
+}
@@ -1,58 +1,147 @@
 package scrape
 
 import (
+	"context"
 	"io"
-	"io/ioutil"
 	"log"
-	"net/http"
+	"net/http/httptrace"
 	"time"
 
+	"github.com/dop251/goja"
 	"github.com/pkg/errors"
 )
 
+// Outcome represents an outcome of a single web page scrape.
+type Outcome string
+
+// Possible scrape outcomes.
+const (
+	OutcomeSuccess Outcome = "success"
+	OutcomeFailure Outcome = "failure"
+)
+
 // Result represent a result of a single web page scrape.
+// A failed scrape has the failure outcome, the error kind and the message.
+// ResponseTimeMs is split into DNS lookup, TCP connect, TLS handshake, time to
+// first byte and transfer phases. The first three are zero for a reused
+// connection. Certificate is only set for HTTPS pages and TLS probes.
+// Redirects contains the followed redirect responses, the phases and the
+// certificate relate to the last response. A scrape with failed assertions
+// has the failure outcome and the assertion error kind, a scrape with a failed
+// script check has the script error kind. Details contains the kind-specific
+// details of probes other than http. Attempts contains the attempts of a
+// retried scrape. A throttled scrape got 429, or 503 with Retry-After, the
+// wait requested with Retry-After is RetryAfterMs.
 type Result struct {
+	Outcome           Outcome
+	ErrorKind         ErrorKind
+	ErrorMessage      string
 	StatusCode        int
 	ResponseSizeBytes int64
 	ResponseTimeMs    int
+	DNSTimeMs         int
+	ConnectTimeMs     int
+	TLSTimeMs         int
+	TTFBMs            int
+	TransferTimeMs    int
+	ConnReused        bool
+	Certificate       *Certificate
+	Redirects         []Redirect
+	Assertions        []AssertionResult
+	Script            *ScriptResult
+	Attempts          []Attempt
+	Throttled         bool
+	RetryAfterMs      int
+	Details           *Details
 	CreatedAt         time.Time
 }
 
-// scraper defines methods to work with a web page scraper.
-type scraper interface {
-	scrape() (Result, error)
+// Details contains the kind-specific details of a scrape, only the field of
+// the scraped kind is set.
+type Details struct {
+	TCP         *TCPDetails         `json:"tcp,omitempty"`
+	DNS         *DNSDetails         `json:"dns,omitempty"`
+	GRPC        *GRPCDetails        `json:"grpc,omitempty"`
+	WebSocket   *WebSocketDetails   `json:"websocket,omitempty"`
+	Exec        *ExecDetails        `json:"exec,omitempty"`
+	Transaction *TransactionDetails `json:"transaction,omitempty"`
+}
+
+// failedResult returns a result of a scrape failed with the given error.
+// The result of a retried scrape records the attempts.
+func failedResult(err error) Result {
+	res := Result{
+		Outcome:      OutcomeFailure,
+		ErrorKind:    kindOf(err),
+		ErrorMessage: err.Error(),
+		CreatedAt:    time.Now(),
+	}
+	var attemptsErr *attemptsError
+	if errors.As(err, &attemptsErr) {
+		res.Attempts = attemptsErr.attempts
+	}
+	return res
 }
 
+// Scraper defines methods to work with a web page scraper.
+type Scraper interface {
+	// Scrape scrapes the target once. An error means the scrape failed, it
+	// is published as a result with the failure outcome. The scrape is
+	// aborted when the context is done.
+	Scrape(ctx context.Context) (Result, error)
+}
+
+// maxAssertedBodySize limits the part of a response body the assertions are
+// evaluated against.
+const maxAssertedBodySize = 1 << 20
+
 // HTTPScraper represents a web page scraper that access the page by the given
 // URL via http and gathers metrics from it.
 type HTTPScraper struct {
 	client httpClient
-	url    string
+	target Target
+	script *goja.Program
 }
 
 // newHTTPScraper returns a new HTTPScraper with the given params.
-func newHTTPScraper(client httpClient, url string) *HTTPScraper {
+func newHTTPScraper(client httpClient, target Target) *HTTPScraper {
 	return &HTTPScraper{
 		client: client,
-		url:    url,
+		target: target,
 	}
 }
 
-// scrape retrieves ranks and returns the ranks or an error not
+// Scrape retrieves ranks and returns the ranks or an error not
 // longer than the configured timeout.
-func (c *HTTPScraper) scrape() (Result, error) {
-	// 1. Create a new http request with the scraper url
-	req, err := http.NewRequest(http.MethodGet, c.url, nil)
+func (c *HTTPScraper) Scrape(ctx context.Context) (Result, error) {
+	// 1. Create a new http request to the scraper target
+	req, err := c.target.newRequest(ctx)
 	if err != nil {
-		return Result{},
-			errors.Wrapf(err, "failed to create request for %s", c.url)
+		return Result{}, newError(
+			ErrorKindInvalidURL,
+			errors.Wrapf(err, "failed to create request for %s", c.target.URL),
+		)
 	}
+	if req.URL.Scheme != "http" && req.URL.Scheme != "https" || req.URL.Host == "" {
+		return Result{}, newError(
+			ErrorKindInvalidURL,
+			errors.Errorf("unsupported url %s", c.target.URL),
+		)
+	}
+
+	trace := &phaseTrace{}
+	redirects := newRedirectRecorder(c.target.redirectPolicy())
+	ctx = httptrace.WithClientTrace(req.Context(), trace.clientTrace())
+	req = req.WithContext(withRedirects(ctx, redirects))
 
 	start := time.Now()
 	// 2. Use the scraper client to do the request
 	resp, err := c.client.Do(req)
 	if err != nil {
-		return Result{}, errors.Wrapf(err, "request failed for %s", c.url)
+		return Result{}, newError(
+			classifyRequestError(err),
+			errors.Wrapf(err, "request failed for %s", c.target.URL),
+		)
 	}
 
 	defer func() {
@@ -61,21 +150,75 @@ func (c *HTTPScraper) scrape() (Result, error) {
 		}
 	}()
 
-	// 3. Calculate the result body size
-	bytes, err := io.Copy(ioutil.Discard, resp.Body)
+	// 3. Calculate the result body size, keep the body for assertions
+	body := &limitedBuffer{}
+	if len(c.target.Assertions) > 0 || c.script != nil {
+		body.limit = maxAssertedBodySize
+	}
+	bytes, err := io.Copy(body, resp.Body)
 	if err != nil {
-		return Result{},
-			errors.Wrapf(err, "failed to read response for %s", c.url)
+		return Result{}, newError(
+			classifyReadError(err),
+			errors.Wrapf(err, "failed to read response for %s", c.target.URL),
+		)
 	}
 
 	// 4. Measure the time and assemble the Metric
-	responseTime := int(time.Since(start).Milliseconds())
+	end := time.Now()
+	responseTime := int(end.Sub(start).Milliseconds())
+	p := trace.phases(end)
 	m := Result{
+		Outcome:           OutcomeSuccess,
 		StatusCode:        resp.StatusCode,
 		ResponseSizeBytes: bytes,
 		ResponseTimeMs:    responseTime,
+		DNSTimeMs:         int(p.dns.Milliseconds()),
+		ConnectTimeMs:     int(p.connect.Milliseconds()),
+		TLSTimeMs:         int(p.tls.Milliseconds()),
+		TTFBMs:            int(p.ttfb.Milliseconds()),
+		TransferTimeMs:    int(p.transfer.Milliseconds()),
+		ConnReused:        p.connReused,
+		Certificate:       leafCertificate(resp.TLS),
+		Redirects:         redirects.chain(),
 		CreatedAt:         time.Now(),
 	}
+	var retryAfter time.Duration
+	m.Throttled, retryAfter = throttled(resp, end)
+	m.RetryAfterMs = int(retryAfter.Milliseconds())
+
+	// 5. Evaluate the assertions against the response
+	if len(c.target.Assertions) > 0 {
+		m.Assertions, err = evaluateAssertions(
+			c.target.Assertions,
+			&response{
+				header:    resp.Header,
+				body:      body.Bytes(),
+				truncated: body.Truncated(),
+			},
+		)
+		if err != nil {
+			m.Outcome = OutcomeFailure
+			m.ErrorKind = ErrorKindAssertion
+			m.ErrorMessage = err.Error()
+		}
+	}
+
+	// 6. Run the script check against the response
+	if c.script != nil {
+		m.Script, err = runScript(c.script, resp.StatusCode, resp.Header, body.Bytes())
+		if m.Outcome == OutcomeSuccess {
+			switch {
+			case err != nil:
+				m.Outcome = OutcomeFailure
+				m.ErrorKind = ErrorKindScript
+				m.ErrorMessage = err.Error()
+			case !m.Script.Passed:
+				m.Outcome = OutcomeFailure
+				m.ErrorKind = ErrorKindScript
+				m.ErrorMessage = m.Script.scriptError()
+			}
+		}
+	}
 
-	return m, err
+	return m, nil
 }
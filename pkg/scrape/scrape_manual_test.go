@@ -1,6 +1,7 @@
 package scrape
 
 import (
+	"context"
 	"io"
 	"io/ioutil"
 	"net/http"
@@ -23,13 +24,19 @@ func TestScraperManual_Scrape(t *testing.T) {
 	const testURL = "https://example.com"
 
 	t.Run("should return error on invalid url", func(t *testing.T) {
-		s := newHTTPScraper(
-			&http.Client{},
-			"http://.invalid url/",
-		)
-		_, err := s.scrape()
+		s := newHTTPScraper(&http.Client{}, Target{URL: "http://.invalid url/"})
+		_, err := s.Scrape(context.Background())
+
+		assert.Error(t, err)
+		assert.Equal(t, ErrorKindInvalidURL, kindOf(err))
+	})
+
+	t.Run("should return error on unsupported scheme", func(t *testing.T) {
+		s := newHTTPScraper(&http.Client{}, Target{URL: "ftp://example.com"})
+		_, err := s.Scrape(context.Background())
 
 		assert.Error(t, err)
+		assert.Equal(t, ErrorKindInvalidURL, kindOf(err))
 	})
 
 	t.Run("should return error on request fail", func(t *testing.T) {
@@ -38,21 +45,23 @@ func TestScraperManual_Scrape(t *testing.T) {
 				return nil, assert.AnError
 			},
 		}
-		s := newHTTPScraper(&client, testURL)
-		_, err := s.scrape()
+		s := newHTTPScraper(&client, Target{URL: testURL})
+		_, err := s.Scrape(context.Background())
 
 		assert.Error(t, err)
 		assert.Regexp(t, testURL, err)
+		assert.Equal(t, ErrorKindUnknown, kindOf(err))
 	})
 
 	t.Run("should return error when fail to read response body", func(t *testing.T) {
 		client := getClientWithStatusAndBody(http.StatusOK, brokenReadCloser{})
 
-		s := newHTTPScraper(client, testURL)
-		_, err := s.scrape()
+		s := newHTTPScraper(client, Target{URL: testURL})
+		_, err := s.Scrape(context.Background())
 
 		assert.Error(t, err)
 		assert.Regexp(t, testURL, err)
+		assert.Equal(t, ErrorKindRead, kindOf(err))
 	})
 
 	t.Run("should return metric when service is unavailable", func(t *testing.T) {
@@ -61,8 +70,8 @@ func TestScraperManual_Scrape(t *testing.T) {
 			http.StatusServiceUnavailable,
 			ioutil.NopCloser(strings.NewReader("")),
 		)
-		s := newHTTPScraper(client, testURL)
-		res, err := s.scrape()
+		s := newHTTPScraper(client, Target{URL: testURL})
+		res, err := s.Scrape(context.Background())
 
 		assert.NoError(t, err)
 		assert.Equal(t, http.StatusServiceUnavailable, res.StatusCode)
@@ -77,10 +86,11 @@ func TestScraperManual_Scrape(t *testing.T) {
 			http.StatusOK,
 			ioutil.NopCloser(strings.NewReader("7 bytes")),
 		)
-		s := newHTTPScraper(client, testURL)
-		res, err := s.scrape()
+		s := newHTTPScraper(client, Target{URL: testURL})
+		res, err := s.Scrape(context.Background())
 
 		assert.NoError(t, err)
+		assert.Equal(t, OutcomeSuccess, res.Outcome)
 		assert.Equal(t, http.StatusOK, res.StatusCode)
 		assert.Equal(t, int64(7), res.ResponseSizeBytes)
 		assert.True(t, res.CreatedAt.After(testStartTime))
@@ -1,6 +1,7 @@
 package scrape
 
 import (
+	"context"
 	"io/ioutil"
 	"net/http"
 	"strconv"
@@ -16,11 +17,8 @@ func TestScraperHttpMock_Scrape(t *testing.T) {
 	const testURL = "https://example.com"
 
 	t.Run("should return error on invalid url", func(t *testing.T) {
-		s := newHTTPScraper(
-			&http.Client{},
-			"http://.invalid url/",
-		)
-		_, err := s.scrape()
+		s := newHTTPScraper(&http.Client{}, Target{URL: "http://.invalid url/"})
+		_, err := s.Scrape(context.Background())
 
 		assert.Error(t, err)
 	})
@@ -35,8 +33,8 @@ func TestScraperHttpMock_Scrape(t *testing.T) {
 			httpmock.NewErrorResponder(assert.AnError),
 		)
 
-		c := newHTTPScraper(&http.Client{}, testURL)
-		_, err := c.scrape()
+		c := newHTTPScraper(&http.Client{}, Target{URL: testURL})
+		_, err := c.Scrape(context.Background())
 
 		assert.Error(t, err)
 		assert.Regexp(t, testURL, err)
@@ -62,8 +60,8 @@ func TestScraperHttpMock_Scrape(t *testing.T) {
 			},
 		)
 
-		s := newHTTPScraper(&http.Client{}, testURL)
-		res, err := s.scrape()
+		s := newHTTPScraper(&http.Client{}, Target{URL: testURL})
+		res, err := s.Scrape(context.Background())
 
 		assert.NoError(t, err)
 		assert.Equal(t, http.StatusServiceUnavailable, res.StatusCode)
@@ -93,8 +91,8 @@ func TestScraperHttpMock_Scrape(t *testing.T) {
 			},
 		)
 
-		s := newHTTPScraper(&http.Client{}, testURL)
-		res, err := s.scrape()
+		s := newHTTPScraper(&http.Client{}, Target{URL: testURL})
+		res, err := s.Scrape(context.Background())
 
 		assert.NoError(t, err)
 		assert.Equal(t, http.StatusOK, res.StatusCode)
@@ -0,0 +1,139 @@
+package scrape
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/dop251/goja"
+	"github.com/pkg/errors"
+)
+
+const (
+	// maxScriptSize limits the size of a script source.
+	maxScriptSize = 64 << 10
+	// scriptTimeout limits the run time of a script, the script is
+	// interrupted once it is exceeded.
+	scriptTimeout = 500 * time.Millisecond
+)
+
+// ScriptResult represents a result of a script check: whether the response
+// passed, an optional message and the custom numeric values.
+type ScriptResult struct {
+	Passed  bool               `json:"passed"`
+	Message string             `json:"message,omitempty"`
+	Values  map[string]float64 `json:"values,omitempty"`
+}
+
+// compileScript compiles the given JavaScript check. An empty script
+// compiles to nil.
+func compileScript(src string) (*goja.Program, error) {
+	if src == "" {
+		return nil, nil
+	}
+	if len(src) > maxScriptSize {
+		return nil, errors.Errorf(
+			"script is larger than %d bytes", maxScriptSize,
+		)
+	}
+	program, err := goja.Compile("script", src, true)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid script")
+	}
+	return program, nil
+}
+
+// runScript runs the compiled check against the given response. The script
+// sees the response as the global object response with the status, headers
+// and body fields and the json() method. The value of its last expression is
+// the result: either a boolean or an object with the pass, message and
+// values fields. An error means the script failed or returned an invalid
+// result.
+func runScript(
+	program *goja.Program, status int, header http.Header, body []byte,
+) (*ScriptResult, error) {
+	vm := goja.New()
+	timer := time.AfterFunc(scriptTimeout, func() {
+		vm.Interrupt(errors.Errorf("script timed out after %s", scriptTimeout))
+	})
+	defer timer.Stop()
+
+	headers := make(map[string]interface{}, len(header))
+	for name, values := range header {
+		headers[strings.ToLower(name)] = strings.Join(values, ", ")
+	}
+	resp := vm.NewObject()
+	_ = resp.Set("status", status)
+	_ = resp.Set("headers", headers)
+	_ = resp.Set("body", string(body))
+	parse, _ := goja.AssertFunction(vm.Get("JSON").ToObject(vm).Get("parse"))
+	_ = resp.Set("json", func() goja.Value {
+		v, err := parse(goja.Undefined(), vm.ToValue(string(body)))
+		if err != nil {
+			var ex *goja.Exception
+			if errors.As(err, &ex) {
+				panic(ex.Value())
+			}
+			panic(vm.NewGoError(err))
+		}
+		return v
+	})
+	_ = vm.Set("response", resp)
+
+	v, err := vm.RunProgram(program)
+	if err != nil {
+		var interrupted *goja.InterruptedError
+		if errors.As(err, &interrupted) {
+			return nil, errors.Wrap(interrupted, "script interrupted")
+		}
+		return nil, errors.Wrap(err, "script failed")
+	}
+	return scriptResult(vm, v)
+}
+
+// scriptResult converts the value returned by a script into a ScriptResult.
+func scriptResult(vm *goja.Runtime, v goja.Value) (*ScriptResult, error) {
+	if v == nil || goja.IsUndefined(v) || goja.IsNull(v) {
+		return nil, errors.New("script returned no result")
+	}
+	if passed, ok := v.Export().(bool); ok {
+		return &ScriptResult{Passed: passed}, nil
+	}
+
+	obj := v.ToObject(vm)
+	pass := obj.Get("pass")
+	if pass == nil {
+		return nil, errors.New("script result has no pass field")
+	}
+	passed, ok := pass.Export().(bool)
+	if !ok {
+		return nil, errors.Errorf("script result pass field %s is not boolean", pass)
+	}
+
+	res := &ScriptResult{Passed: passed}
+	if msg := obj.Get("message"); msg != nil && !goja.IsUndefined(msg) {
+		res.Message = msg.String()
+	}
+	if values := obj.Get("values"); values != nil && !goja.IsUndefined(values) {
+		valuesObj := values.ToObject(vm)
+		res.Values = make(map[string]float64)
+		for _, key := range valuesObj.Keys() {
+			f := valuesObj.Get(key).ToFloat()
+			if math.IsNaN(f) || math.IsInf(f, 0) {
+				return nil, errors.Errorf("script value %s is not a number", key)
+			}
+			res.Values[key] = f
+		}
+	}
+	return res, nil
+}
+
+// scriptError returns the error message of a failed script check.
+func (r *ScriptResult) scriptError() string {
+	if r.Message == "" {
+		return "script check failed"
+	}
+	return fmt.Sprintf("script check failed: %s", r.Message)
+}
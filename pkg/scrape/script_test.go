@@ -0,0 +1,170 @@
+package scrape
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompileScript(t *testing.T) {
+	t.Run("should compile empty script to nil", func(t *testing.T) {
+		p, err := compileScript("")
+
+		require.NoError(t, err)
+		assert.Nil(t, p)
+	})
+
+	t.Run("should return error on syntax error", func(t *testing.T) {
+		_, err := compileScript("response.status ==")
+
+		require.Error(t, err)
+		assert.Regexp(t, "invalid script", err)
+	})
+
+	t.Run("should return error on too large script", func(t *testing.T) {
+		_, err := compileScript(strings.Repeat(" ", maxScriptSize+1) + "true")
+
+		require.Error(t, err)
+		assert.Regexp(t, "larger than", err)
+	})
+}
+
+func TestRunScript(t *testing.T) {
+	header := http.Header{"Content-Type": []string{"application/json"}}
+	body := []byte(`{"items":[{"stale":false},{"stale":false},{"stale":false},{"stale":true}]}`)
+
+	tests := []struct {
+		name    string
+		script  string
+		want    *ScriptResult
+		wantErr string
+	}{
+		{
+			name:   "should accept boolean result",
+			script: "response.status === 200",
+			want:   &ScriptResult{Passed: true},
+		},
+		{
+			name:   "should expose headers by lower case name",
+			script: `response.headers["content-type"] === "application/json"`,
+			want:   &ScriptResult{Passed: true},
+		},
+		{
+			name: "should accept object result with values",
+			script: `
+				const items = response.json().items;
+				const stale = items.filter(function(i) { return i.stale; }).length;
+				({
+					pass: items.length > 3 && stale === 0,
+					message: stale + " stale items",
+					values: {items: items.length, stale: stale},
+				})`,
+			want: &ScriptResult{
+				Passed:  false,
+				Message: "1 stale items",
+				Values:  map[string]float64{"items": 4, "stale": 1},
+			},
+		},
+		{
+			name:    "should return error on exception",
+			script:  `throw new Error("boom")`,
+			wantErr: "boom",
+		},
+		{
+			name:    "should return error on invalid json",
+			script:  `JSON.parse("{").ok`,
+			wantErr: "script failed",
+		},
+		{
+			name:    "should return error on missing result",
+			script:  `var x = 1;`,
+			wantErr: "no result",
+		},
+		{
+			name:    "should return error on result without pass",
+			script:  `({ok: true})`,
+			wantErr: "no pass field",
+		},
+		{
+			name:    "should return error on non numeric value",
+			script:  `({pass: true, values: {a: "b"}})`,
+			wantErr: "not a number",
+		},
+		{
+			name:    "should interrupt endless script",
+			script:  `while (true) {}`,
+			wantErr: "interrupted",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := compileScript(tt.script)
+			require.NoError(t, err)
+
+			res, err := runScript(p, http.StatusOK, header, body)
+
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Regexp(t, tt.wantErr, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, res)
+		})
+	}
+}
+
+func TestHTTPScraper_ScrapeScript(t *testing.T) {
+	client := &clientMock{
+		doMock: func(req *http.Request) (*http.Response, error) {
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Header:     http.Header{},
+				Body:       ioutil.NopCloser(strings.NewReader(testJSONBody)),
+			}, nil
+		},
+	}
+	newScraper := func(t *testing.T, script string) Scraper {
+		s, err := httpFactory(client)(Target{URL: "https://example.com", Script: script})
+		require.NoError(t, err)
+		return s
+	}
+
+	t.Run("should succeed when script passes", func(t *testing.T) {
+		res, err := newScraper(t, `({pass: true, values: {count: response.json().count}})`).Scrape(context.Background())
+
+		require.NoError(t, err)
+		assert.Equal(t, OutcomeSuccess, res.Outcome)
+		assert.Equal(t, &ScriptResult{Passed: true, Values: map[string]float64{"count": 1}}, res.Script)
+	})
+
+	t.Run("should fail when script does not pass", func(t *testing.T) {
+		res, err := newScraper(t, `({pass: false, message: "too few"})`).Scrape(context.Background())
+
+		require.NoError(t, err)
+		assert.Equal(t, OutcomeFailure, res.Outcome)
+		assert.Equal(t, ErrorKindScript, res.ErrorKind)
+		assert.Regexp(t, "too few", res.ErrorMessage)
+	})
+
+	t.Run("should fail when script throws", func(t *testing.T) {
+		res, err := newScraper(t, `response.json().missing.field`).Scrape(context.Background())
+
+		require.NoError(t, err)
+		assert.Equal(t, OutcomeFailure, res.Outcome)
+		assert.Equal(t, ErrorKindScript, res.ErrorKind)
+		assert.Nil(t, res.Script)
+	})
+
+	t.Run("should return error on invalid script", func(t *testing.T) {
+		_, err := httpFactory(client)(Target{URL: "https://example.com", Script: "("})
+
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,177 @@
+package scrape
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// headerNameRe matches valid http header names.
+var headerNameRe = regexp.MustCompile("^[!#$%&'*+.^_`|~0-9A-Za-z-]+$")
+
+// Target describes a web page to scrape and how to scrape it.
+type Target struct {
+	// Kind defines the kind of probe, http if empty.
+	Kind     Kind
+	URL      string
+	Interval time.Duration
+	// Timeout limits every scrape in addition to the client and probe
+	// timeouts, if positive.
+	Timeout time.Duration
+	// Settings contains kind-specific settings decoded by the kind factory.
+	Settings json.RawMessage
+	// Method is the http method of the request, GET if empty.
+	Method string
+	// Headers are set on every request, the Host header overrides the
+	// request host.
+	Headers map[string]string
+	Body    string
+	// Auth is applied to every request after the headers, if set.
+	Auth *Auth
+	// RedirectPolicy defines how redirects are followed, if nil up to 10
+	// redirects are followed.
+	RedirectPolicy *RedirectPolicy
+	// Assertions are evaluated against every response, the scrape fails if
+	// any of them does not pass.
+	Assertions []Assertion
+	// Script is a JavaScript check run against every response, the scrape
+	// fails if it does not pass.
+	Script string
+	// Retry defines how failed scrapes of any kind are retried, if nil they
+	// are not.
+	Retry *RetryPolicy
+	// Failing defines the scraping interval while the target is failing, if
+	// nil it is scraped every Interval.
+	Failing *FailingPolicy
+}
+
+// Validate checks that the target describes a valid request. Only http
+// targets are checked, targets of other kinds are validated by the factory of
+// their kind.
+func (t Target) Validate() error {
+	if t.kind() != KindHTTP {
+		return nil
+	}
+
+	switch t.method() {
+	case http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut,
+		http.MethodPatch, http.MethodDelete, http.MethodOptions:
+	default:
+		return errors.Errorf("unsupported method %q", t.Method)
+	}
+
+	if err := validateHeaders(t.Headers); err != nil {
+		return err
+	}
+
+	if t.Body != "" && (t.method() == http.MethodGet || t.method() == http.MethodHead) {
+		return errors.Errorf("method %s does not allow a body", t.method())
+	}
+
+	if t.Auth != nil {
+		if err := t.Auth.Validate(); err != nil {
+			return err
+		}
+	}
+
+	if t.RedirectPolicy != nil {
+		if err := t.RedirectPolicy.Validate(); err != nil {
+			return err
+		}
+	}
+
+	for _, a := range t.Assertions {
+		if err := a.Validate(); err != nil {
+			return err
+		}
+	}
+
+	if _, err := compileScript(t.Script); err != nil {
+		return err
+	}
+	return nil
+}
+
+// validateHeaders checks that the given request headers have valid names and
+// single-line values.
+func validateHeaders(headers map[string]string) error {
+	for name, value := range headers {
+		if !headerNameRe.MatchString(name) {
+			return errors.Errorf("invalid header name %q", name)
+		}
+		if strings.ContainsAny(value, "\r\n") {
+			return errors.Errorf("invalid value of header %q", name)
+		}
+	}
+	return nil
+}
+
+// validateNonHTTP checks that the target of a kind other than http does not
+// define an http request or a script.
+func (t Target) validateNonHTTP() error {
+	if t.Method != "" || len(t.Headers) > 0 || t.Body != "" ||
+		t.Auth != nil || t.RedirectPolicy != nil || t.Script != "" {
+		return errors.Errorf(
+			"method, headers, body, auth, redirect policy and script are not supported by %s probes",
+			t.kind(),
+		)
+	}
+	return nil
+}
+
+// kind returns the probe kind of the target.
+func (t Target) kind() Kind {
+	if t.Kind == "" {
+		return KindHTTP
+	}
+	return t.Kind
+}
+
+// redirectPolicy returns the redirect policy of the target.
+func (t Target) redirectPolicy() RedirectPolicy {
+	if t.RedirectPolicy == nil {
+		return RedirectPolicy{Follow: true}
+	}
+	return *t.RedirectPolicy
+}
+
+// method returns the http method of the request.
+func (t Target) method() string {
+	if t.Method == "" {
+		return http.MethodGet
+	}
+	return t.Method
+}
+
+// newRequest creates a new http request to the target with the given
+// context.
+func (t Target) newRequest(ctx context.Context) (*http.Request, error) {
+	var body io.Reader
+	if t.Body != "" {
+		body = strings.NewReader(t.Body)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, t.method(), t.URL, body)
+	if err != nil {
+		return nil, err
+	}
+
+	for name, value := range t.Headers {
+		if strings.EqualFold(name, "Host") {
+			req.Host = value
+			continue
+		}
+		req.Header.Set(name, value)
+	}
+
+	if t.Auth != nil {
+		t.Auth.apply(req)
+	}
+	return req, nil
+}
@@ -0,0 +1,125 @@
+package scrape
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTarget_Validate(t *testing.T) {
+	tests := []struct {
+		name   string
+		target Target
+		err    string
+	}{
+		{
+			name:   "unsupported method",
+			target: Target{Method: "CONNECT"},
+			err:    "unsupported method",
+		},
+		{
+			name:   "lower case method",
+			target: Target{Method: "post"},
+			err:    "unsupported method",
+		},
+		{
+			name:   "invalid header name",
+			target: Target{Headers: map[string]string{"User Agent": "webapp101"}},
+			err:    "invalid header name",
+		},
+		{
+			name:   "invalid header value",
+			target: Target{Headers: map[string]string{"Accept": "text/html\r\nX-Injected: 1"}},
+			err:    "invalid value of header",
+		},
+		{
+			name:   "body with GET",
+			target: Target{Body: "{}"},
+			err:    "does not allow a body",
+		},
+		{
+			name: "invalid assertion",
+			target: Target{
+				Assertions: []Assertion{{Type: AssertionRegex, Value: "("}},
+			},
+			err: "invalid regex",
+		},
+		{
+			name: "valid",
+			target: Target{
+				Method:  http.MethodPost,
+				Headers: map[string]string{"Accept": "application/json"},
+				Body:    "{}",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.target.Validate()
+			if tt.err == "" {
+				assert.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			assert.Regexp(t, tt.err, err)
+		})
+	}
+}
+
+func TestHTTPScraper_ScrapeRequest(t *testing.T) {
+	t.Run("should send GET by default", func(t *testing.T) {
+		var method string
+		server := httptest.NewServer(http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {
+				method = r.Method
+			},
+		))
+		defer server.Close()
+
+		s := newHTTPScraper(server.Client(), Target{URL: server.URL})
+		_, err := s.Scrape(context.Background())
+
+		require.NoError(t, err)
+		assert.Equal(t, http.MethodGet, method)
+	})
+
+	t.Run("should send configured method, headers and body", func(t *testing.T) {
+		var (
+			received *http.Request
+			body     []byte
+		)
+		server := httptest.NewServer(http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {
+				received = r
+				body, _ = ioutil.ReadAll(r.Body)
+			},
+		))
+		defer server.Close()
+
+		s := newHTTPScraper(server.Client(), Target{
+			URL:    server.URL,
+			Method: http.MethodPost,
+			Headers: map[string]string{
+				"User-Agent":   "webapp101",
+				"Content-Type": "application/json",
+				"Host":         "health.example.com",
+			},
+			Body: `{"check":"deep"}`,
+		})
+		_, err := s.Scrape(context.Background())
+
+		require.NoError(t, err)
+		assert.Equal(t, http.MethodPost, received.Method)
+		assert.Equal(t, "webapp101", received.UserAgent())
+		assert.Equal(t, "application/json", received.Header.Get("Content-Type"))
+		assert.Equal(t, "health.example.com", received.Host)
+		assert.Equal(t, `{"check":"deep"}`, string(body))
+	})
+}
@@ -0,0 +1,268 @@
+package scrape
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+)
+
+// KindTCP is the kind of TCPScraper.
+const KindTCP Kind = "tcp"
+
+// maxDetailsResponseSize limits the part of a response kept in the details.
+const maxDetailsResponseSize = 256
+
+// printable returns the response as text with non-printable bytes and
+// invalid UTF-8 escaped as \xNN, so that binary responses can be stored in
+// JSONB, which rejects NUL characters.
+func printable(b []byte) string {
+	var sb strings.Builder
+	for len(b) > 0 {
+		r, size := utf8.DecodeRune(b)
+		if r == utf8.RuneError && size <= 1 ||
+			!unicode.IsPrint(r) && r != '\n' && r != '\r' && r != '\t' {
+			for _, c := range b[:size] {
+				fmt.Fprintf(&sb, "\\x%02x", c)
+			}
+		} else {
+			sb.Write(b[:size])
+		}
+		b = b[size:]
+	}
+	return sb.String()
+}
+
+// TCPSettings defines the settings of a tcp target. Send is written to the
+// connection once it is established. Expect and ExpectRegex are checked
+// against the banner or the response, the scrape fails if they do not match.
+// Timeout limits the whole scrape, 10s if empty.
+type TCPSettings struct {
+	Send        string `json:"send,omitempty"`
+	Expect      string `json:"expect,omitempty"`
+	ExpectRegex string `json:"expect_regex,omitempty"`
+	Timeout     string `json:"timeout,omitempty"`
+}
+
+// TCPDetails contains the details of a tcp scrape: the address of the peer
+// and the beginning of the banner or the response.
+type TCPDetails struct {
+	RemoteAddr string `json:"remote_addr"`
+	Response   string `json:"response,omitempty"`
+}
+
+type dialer interface {
+	DialContext(ctx context.Context, network, address string) (net.Conn, error)
+}
+
+// TCPScraper represents a scraper that connects to the host:port given as the
+// target URL. It measures the connect latency and, if configured, sends a
+// payload and checks the response.
+type TCPScraper struct {
+	dialer     dialer
+	address    string
+	send       string
+	timeout    time.Duration
+	assertions []Assertion
+}
+
+// tcpFactory returns a factory of TCPScrapers that use the given dialer.
+func tcpFactory(d dialer) Factory {
+	return func(target Target) (Scraper, error) {
+		if err := target.validateNonHTTP(); err != nil {
+			return nil, err
+		}
+		if err := validateAddress(target.URL); err != nil {
+			return nil, err
+		}
+
+		var settings TCPSettings
+		if err := decodeSettings(target.Settings, &settings); err != nil {
+			return nil, err
+		}
+		timeout, err := parseTimeout(settings.Timeout)
+		if err != nil {
+			return nil, err
+		}
+
+		assertions := append(
+			expectAssertions(settings.Expect, settings.ExpectRegex),
+			target.Assertions...,
+		)
+		if err := validateBodyAssertions(assertions); err != nil {
+			return nil, err
+		}
+
+		return &TCPScraper{
+			dialer:     d,
+			address:    target.URL,
+			send:       settings.Send,
+			timeout:    timeout,
+			assertions: compileAssertions(assertions),
+		}, nil
+	}
+}
+
+// Scrape connects to the target address. If a payload is configured it is
+// sent, and if there is a payload or an expectation the response is read
+// until all the expectations pass, the peer closes the connection or the
+// timeout expires.
+func (s *TCPScraper) Scrape(ctx context.Context) (Result, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	// 1. Connect to the target
+	start := time.Now()
+	conn, err := s.dialer.DialContext(ctx, "tcp", s.address)
+	if err != nil {
+		return Result{}, newError(
+			classifyRequestError(err),
+			errors.Wrapf(err, "failed to connect to %s", s.address),
+		)
+	}
+	connected := time.Now()
+
+	defer func() {
+		if err = conn.Close(); err != nil {
+			log.Printf("failed to close connection, %s", err)
+		}
+	}()
+
+	m := Result{
+		Outcome:        OutcomeSuccess,
+		ConnectTimeMs:  int(connected.Sub(start).Milliseconds()),
+		ResponseTimeMs: int(connected.Sub(start).Milliseconds()),
+		Details: &Details{
+			TCP: &TCPDetails{RemoteAddr: conn.RemoteAddr().String()},
+		},
+		CreatedAt: time.Now(),
+	}
+	if s.send == "" && len(s.assertions) == 0 {
+		return m, nil
+	}
+
+	deadline, _ := ctx.Deadline()
+	if err = conn.SetDeadline(deadline); err != nil {
+		return Result{}, errors.Wrapf(err, "failed to set deadline for %s", s.address)
+	}
+	defer interruptOnCancel(ctx, conn)()
+
+	// 2. Send the payload
+	if s.send != "" {
+		if _, err = io.WriteString(conn, s.send); err != nil {
+			return Result{}, newError(
+				classifyReadError(err),
+				errors.Wrapf(err, "failed to send payload to %s", s.address),
+			)
+		}
+	}
+	sent := time.Now()
+
+	// 3. Read the response and evaluate the expectations
+	body := &limitedBuffer{limit: maxAssertedBodySize}
+	var firstByte time.Time
+	chunk := make([]byte, 4096)
+	for {
+		n, err := conn.Read(chunk)
+		if n > 0 {
+			if firstByte.IsZero() {
+				firstByte = time.Now()
+			}
+			m.ResponseSizeBytes += int64(n)
+			_, _ = body.Write(chunk[:n])
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			if isTimeout(err) && m.ResponseSizeBytes > 0 {
+				// evaluate what has been received so far.
+				break
+			}
+			return Result{}, newError(
+				classifyReadError(err),
+				errors.Wrapf(err, "failed to read response from %s", s.address),
+			)
+		}
+		if n > 0 && s.satisfied(body.Bytes()) {
+			break
+		}
+	}
+
+	// 4. Measure the time and assemble the result
+	end := time.Now()
+	m.ResponseTimeMs = int(end.Sub(start).Milliseconds())
+	if !firstByte.IsZero() {
+		m.TTFBMs = int(firstByte.Sub(sent).Milliseconds())
+		m.TransferTimeMs = int(end.Sub(firstByte).Milliseconds())
+	}
+	banner := body.Bytes()
+	if len(banner) > maxDetailsResponseSize {
+		banner = banner[:maxDetailsResponseSize]
+	}
+	m.Details.TCP.Response = printable(banner)
+
+	if len(s.assertions) > 0 {
+		m.Assertions, err = evaluateAssertions(
+			s.assertions,
+			&response{body: body.Bytes(), truncated: body.Truncated()},
+		)
+		if err != nil {
+			m.Outcome = OutcomeFailure
+			m.ErrorKind = ErrorKindAssertion
+			m.ErrorMessage = err.Error()
+		}
+	}
+
+	return m, nil
+}
+
+// satisfied reports whether enough of the response has been read: the first
+// chunk without expectations, or the part that satisfies all of them.
+func (s *TCPScraper) satisfied(body []byte) bool {
+	if len(s.assertions) == 0 {
+		return true
+	}
+	if len(body) >= maxAssertedBodySize {
+		return true
+	}
+	_, err := evaluateAssertions(s.assertions, &response{body: body})
+	return err == nil
+}
+
+// validateAddress checks that the given address has the host:port form.
+func validateAddress(address string) error {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return errors.Wrapf(err, "invalid address %q", address)
+	}
+	if host == "" {
+		return errors.Errorf("address %q has no host", address)
+	}
+	if p, err := strconv.Atoi(port); err != nil || p <= 0 || p > 65535 {
+		return errors.Errorf("address %q has invalid port", address)
+	}
+	return nil
+}
+
+// interruptOnCancel interrupts pending reads and writes on the connection
+// when the context is canceled before the returned stop function is called.
+func interruptOnCancel(ctx context.Context, conn net.Conn) (stop func()) {
+	done := make(chan struct{})
+	go func() {
+		select {
+		case <-ctx.Done():
+			_ = conn.SetDeadline(time.Now())
+		case <-done:
+		}
+	}()
+	return func() { close(done) }
+}
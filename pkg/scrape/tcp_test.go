@@ -0,0 +1,270 @@
+package scrape
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTCPFactory(t *testing.T) {
+	factory := tcpFactory(&net.Dialer{})
+
+	tests := []struct {
+		name    string
+		target  Target
+		wantErr string
+	}{
+		{
+			name:    "should return error on missing port",
+			target:  Target{Kind: KindTCP, URL: "example.com"},
+			wantErr: "invalid address",
+		},
+		{
+			name:    "should return error on missing host",
+			target:  Target{Kind: KindTCP, URL: ":6379"},
+			wantErr: "no host",
+		},
+		{
+			name:    "should return error on invalid port",
+			target:  Target{Kind: KindTCP, URL: "localhost:http"},
+			wantErr: "invalid port",
+		},
+		{
+			name: "should return error on http request fields",
+			target: Target{
+				Kind: KindTCP, URL: "localhost:6379", Method: "POST",
+			},
+			wantErr: "not supported",
+		},
+		{
+			name: "should return error on script",
+			target: Target{
+				Kind: KindTCP, URL: "localhost:6379", Script: "check(true)",
+			},
+			wantErr: "script are not supported",
+		},
+		{
+			name: "should return error on unknown setting",
+			target: Target{
+				Kind:     KindTCP,
+				URL:      "localhost:6379",
+				Settings: json.RawMessage(`{"sned":"PING"}`),
+			},
+			wantErr: "unknown field",
+		},
+		{
+			name: "should return error on invalid timeout",
+			target: Target{
+				Kind:     KindTCP,
+				URL:      "localhost:6379",
+				Settings: json.RawMessage(`{"timeout":"-1s"}`),
+			},
+			wantErr: "must be positive",
+		},
+		{
+			name: "should return error on invalid regex",
+			target: Target{
+				Kind:     KindTCP,
+				URL:      "localhost:6379",
+				Settings: json.RawMessage(`{"expect_regex":"("}`),
+			},
+			wantErr: "invalid regex",
+		},
+		{
+			name: "should return error on header assertion",
+			target: Target{
+				Kind: KindTCP,
+				URL:  "localhost:6379",
+				Assertions: []Assertion{
+					{Type: AssertionHeaderEquals, Header: "X", Value: "y"},
+				},
+			},
+			wantErr: "not supported",
+		},
+		{
+			name: "should create scraper",
+			target: Target{
+				Kind:     KindTCP,
+				URL:      "localhost:6379",
+				Settings: json.RawMessage(`{"send":"PING\r\n","expect":"+PONG"}`),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := factory(tt.target)
+
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Regexp(t, tt.wantErr, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.IsType(t, &TCPScraper{}, s)
+		})
+	}
+}
+
+func TestTCPScraper_Scrape(t *testing.T) {
+	t.Run("should return connect error", func(t *testing.T) {
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		addr := l.Addr().String()
+		require.NoError(t, l.Close())
+
+		_, err = newTestTCPScraper(t, addr, "").Scrape(context.Background())
+
+		require.Error(t, err)
+		assert.Equal(t, ErrorKindConnect, kindOf(err))
+	})
+
+	t.Run("should connect without reading", func(t *testing.T) {
+		addr := startTCPServer(t, func(conn net.Conn) {
+			// nothing is ever sent.
+			time.Sleep(time.Second)
+		})
+
+		res, err := newTestTCPScraper(t, addr, "").Scrape(context.Background())
+
+		require.NoError(t, err)
+		assert.Equal(t, OutcomeSuccess, res.Outcome)
+		assert.Equal(t, addr, res.Details.TCP.RemoteAddr)
+		assert.Empty(t, res.Details.TCP.Response)
+	})
+
+	t.Run("should check banner", func(t *testing.T) {
+		addr := startTCPServer(t, func(conn net.Conn) {
+			_, _ = conn.Write([]byte("220 smtp.example.com ESMTP\r\n"))
+			// the connection is kept open, the scrape must not wait for it.
+			time.Sleep(time.Second)
+		})
+
+		start := time.Now()
+		res, err := newTestTCPScraper(
+			t, addr, `{"expect_regex":"^220 ","timeout":"5s"}`,
+		).Scrape(context.Background())
+
+		require.NoError(t, err)
+		assert.Less(t, int64(time.Since(start)), int64(time.Second))
+		assert.Equal(t, OutcomeSuccess, res.Outcome)
+		assert.Equal(t, "220 smtp.example.com ESMTP\r\n", res.Details.TCP.Response)
+		require.Len(t, res.Assertions, 1)
+		assert.True(t, res.Assertions[0].Passed)
+	})
+
+	t.Run("should escape binary banner", func(t *testing.T) {
+		addr := startTCPServer(t, func(conn net.Conn) {
+			_, _ = conn.Write([]byte("J\x00\x00\x00\n8.0.23\x00\xff\r\n"))
+		})
+
+		res, err := newTestTCPScraper(
+			t, addr, `{"expect":"\r\n"}`,
+		).Scrape(context.Background())
+
+		require.NoError(t, err)
+		assert.Equal(t,
+			`J\x00\x00\x00`+"\n"+`8.0.23\x00\xff`+"\r\n",
+			res.Details.TCP.Response,
+		)
+		assert.NotContains(t, res.Details.TCP.Response, "\x00")
+	})
+
+	t.Run("should send payload and check response", func(t *testing.T) {
+		addr := startTCPServer(t, func(conn net.Conn) {
+			line, err := bufio.NewReader(conn).ReadString('\n')
+			if err == nil && line == "PING\r\n" {
+				_, _ = conn.Write([]byte("+PONG\r\n"))
+			}
+		})
+
+		res, err := newTestTCPScraper(
+			t, addr, `{"send":"PING\r\n","expect":"+PONG"}`,
+		).Scrape(context.Background())
+
+		require.NoError(t, err)
+		assert.Equal(t, OutcomeSuccess, res.Outcome)
+		assert.Equal(t, int64(7), res.ResponseSizeBytes)
+	})
+
+	t.Run("should fail when response does not match", func(t *testing.T) {
+		addr := startTCPServer(t, func(conn net.Conn) {
+			_, _ = conn.Write([]byte("-ERR unknown command\r\n"))
+		})
+
+		res, err := newTestTCPScraper(
+			t, addr, `{"send":"PING\r\n","expect":"+PONG"}`,
+		).Scrape(context.Background())
+
+		require.NoError(t, err)
+		assert.Equal(t, OutcomeFailure, res.Outcome)
+		assert.Equal(t, ErrorKindAssertion, res.ErrorKind)
+		assert.Equal(t, "-ERR unknown command\r\n", res.Details.TCP.Response)
+	})
+
+	t.Run("should evaluate partial response on timeout", func(t *testing.T) {
+		addr := startTCPServer(t, func(conn net.Conn) {
+			_, _ = conn.Write([]byte("+PO"))
+			time.Sleep(time.Second)
+		})
+
+		res, err := newTestTCPScraper(
+			t, addr, `{"expect":"+PONG","timeout":"100ms"}`,
+		).Scrape(context.Background())
+
+		require.NoError(t, err)
+		assert.Equal(t, OutcomeFailure, res.Outcome)
+		assert.Equal(t, ErrorKindAssertion, res.ErrorKind)
+	})
+
+	t.Run("should return timeout error when nothing is received", func(t *testing.T) {
+		addr := startTCPServer(t, func(conn net.Conn) {
+			time.Sleep(time.Second)
+		})
+
+		_, err := newTestTCPScraper(
+			t, addr, `{"expect":"+PONG","timeout":"100ms"}`,
+		).Scrape(context.Background())
+
+		require.Error(t, err)
+		assert.Equal(t, ErrorKindTimeout, kindOf(err))
+	})
+}
+
+func newTestTCPScraper(t *testing.T, addr, settings string) Scraper {
+	target := Target{Kind: KindTCP, URL: addr}
+	if settings != "" {
+		target.Settings = json.RawMessage(settings)
+	}
+	s, err := tcpFactory(&net.Dialer{})(target)
+	require.NoError(t, err)
+	return s
+}
+
+// startTCPServer starts a local tcp server that handles every connection with
+// the given handler and closes the connection afterwards.
+func startTCPServer(t *testing.T, handle func(conn net.Conn)) string {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = l.Close() })
+
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				handle(conn)
+			}()
+		}
+	}()
+	return l.Addr().String()
+}
@@ -0,0 +1,80 @@
+package scrape
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// maxThrottleBackoff limits how much the scraping interval of a throttled
+	// target grows without Retry-After.
+	maxThrottleBackoff = 16
+	// maxRetryAfter limits the wait requested by a target with Retry-After.
+	maxRetryAfter = time.Hour
+)
+
+// throttled reports whether the response means the target rate limits the
+// scraper: 429, or 503 with Retry-After. It returns the wait requested with
+// Retry-After, zero if there is none.
+func throttled(resp *http.Response, now time.Time) (bool, time.Duration) {
+	retryAfter, ok := parseRetryAfter(resp.Header.Get("Retry-After"), now)
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests:
+		return true, retryAfter
+	case resp.StatusCode == http.StatusServiceUnavailable && ok:
+		return true, retryAfter
+	}
+	return false, 0
+}
+
+// parseRetryAfter parses the value of the Retry-After header, either delay
+// seconds or an http date. The wait is limited by maxRetryAfter.
+func parseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, false
+	}
+
+	var d time.Duration
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		d = time.Duration(seconds) * time.Second
+	} else if date, err := http.ParseTime(value); err == nil {
+		d = date.Sub(now)
+		if d < 0 {
+			d = 0
+		}
+	} else {
+		return 0, false
+	}
+
+	if d > maxRetryAfter {
+		d = maxRetryAfter
+	}
+	return d, true
+}
+
+// throttledInterval returns the scraping interval after the given number of
+// consecutive throttled scrapes. The wait requested with Retry-After is used
+// if it is longer than the interval, otherwise the interval doubles with
+// every throttled scrape up to maxThrottleBackoff times the interval.
+func throttledInterval(
+	interval time.Duration, throttles int, retryAfter time.Duration,
+) time.Duration {
+	if retryAfter > 0 {
+		if retryAfter > interval {
+			return retryAfter
+		}
+		return interval
+	}
+
+	factor := 1
+	for i := 0; i < throttles && factor < maxThrottleBackoff; i++ {
+		factor *= 2
+	}
+	return interval * time.Duration(factor)
+}
@@ -0,0 +1,94 @@
+package scrape
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestThrottled(t *testing.T) {
+	now := time.Date(2021, 4, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name           string
+		status         int
+		retryAfter     string
+		wantThrottled  bool
+		wantRetryAfter time.Duration
+	}{
+		{
+			name:          "should not throttle on ok",
+			status:        http.StatusOK,
+			retryAfter:    "10",
+			wantThrottled: false,
+		},
+		{
+			name:          "should throttle on too many requests",
+			status:        http.StatusTooManyRequests,
+			wantThrottled: true,
+		},
+		{
+			name:           "should parse retry after seconds",
+			status:         http.StatusTooManyRequests,
+			retryAfter:     "120",
+			wantThrottled:  true,
+			wantRetryAfter: 2 * time.Minute,
+		},
+		{
+			name:           "should parse retry after date",
+			status:         http.StatusServiceUnavailable,
+			retryAfter:     now.Add(30 * time.Second).Format(http.TimeFormat),
+			wantThrottled:  true,
+			wantRetryAfter: 30 * time.Second,
+		},
+		{
+			name:           "should limit retry after",
+			status:         http.StatusTooManyRequests,
+			retryAfter:     "86400",
+			wantThrottled:  true,
+			wantRetryAfter: maxRetryAfter,
+		},
+		{
+			name:          "should ignore invalid retry after",
+			status:        http.StatusTooManyRequests,
+			retryAfter:    "soon",
+			wantThrottled: true,
+		},
+		{
+			name:          "should not throttle on unavailable without retry after",
+			status:        http.StatusServiceUnavailable,
+			wantThrottled: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := &http.Response{StatusCode: tt.status, Header: http.Header{}}
+			if tt.retryAfter != "" {
+				resp.Header.Set("Retry-After", tt.retryAfter)
+			}
+
+			throttled, retryAfter := throttled(resp, now)
+
+			assert.Equal(t, tt.wantThrottled, throttled)
+			assert.Equal(t, tt.wantRetryAfter, retryAfter)
+		})
+	}
+}
+
+func TestThrottledInterval(t *testing.T) {
+	t.Run("should back off interval", func(t *testing.T) {
+		assert.Equal(t, 2*time.Minute, throttledInterval(time.Minute, 1, 0))
+		assert.Equal(t, 8*time.Minute, throttledInterval(time.Minute, 3, 0))
+		assert.Equal(t,
+			maxThrottleBackoff*time.Minute,
+			throttledInterval(time.Minute, 100, 0),
+		)
+	})
+
+	t.Run("should use retry after longer than interval", func(t *testing.T) {
+		assert.Equal(t, 5*time.Minute, throttledInterval(time.Minute, 1, 5*time.Minute))
+		assert.Equal(t, time.Minute, throttledInterval(time.Minute, 1, time.Second))
+	})
+}
@@ -0,0 +1,121 @@
+package scrape
+
+import (
+	"crypto/tls"
+	"net/http/httptrace"
+	"sync"
+	"time"
+)
+
+// phaseTrace records timestamps of the phases of a single http request.
+// The trace hooks might be called from different goroutines, hence the access
+// is guarded by the mutex.
+type phaseTrace struct {
+	mu           sync.Mutex
+	dnsStart     time.Time
+	dnsDone      time.Time
+	connectStart time.Time
+	connectDone  time.Time
+	tlsStart     time.Time
+	tlsDone      time.Time
+	gotConn      time.Time
+	wroteRequest time.Time
+	firstByte    time.Time
+	connReused   bool
+}
+
+// phases contains durations of the phases of a single http request.
+type phases struct {
+	dns        time.Duration
+	connect    time.Duration
+	tls        time.Duration
+	ttfb       time.Duration
+	transfer   time.Duration
+	connReused bool
+}
+
+// clientTrace returns hooks that record the phase timestamps.
+func (t *phaseTrace) clientTrace() *httptrace.ClientTrace {
+	return &httptrace.ClientTrace{
+		// every redirect starts a new request, only the last one is traced.
+		GetConn:  func(string) { t.reset() },
+		DNSStart: func(httptrace.DNSStartInfo) { t.record(&t.dnsStart) },
+		DNSDone:  func(httptrace.DNSDoneInfo) { t.record(&t.dnsDone) },
+		// with multiple addresses several dials may happen, the first start
+		// and the last done are recorded.
+		ConnectStart: func(_, _ string) { t.recordOnce(&t.connectStart) },
+		ConnectDone:  func(_, _ string, _ error) { t.record(&t.connectDone) },
+		TLSHandshakeStart: func() {
+			t.record(&t.tlsStart)
+		},
+		TLSHandshakeDone: func(tls.ConnectionState, error) {
+			t.record(&t.tlsDone)
+		},
+		GotConn: func(info httptrace.GotConnInfo) {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			t.gotConn = time.Now()
+			t.connReused = info.Reused
+		},
+		WroteRequest: func(httptrace.WroteRequestInfo) {
+			t.record(&t.wroteRequest)
+		},
+		GotFirstResponseByte: func() { t.record(&t.firstByte) },
+	}
+}
+
+func (t *phaseTrace) reset() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.dnsStart, t.dnsDone = time.Time{}, time.Time{}
+	t.connectStart, t.connectDone = time.Time{}, time.Time{}
+	t.tlsStart, t.tlsDone = time.Time{}, time.Time{}
+	t.gotConn, t.wroteRequest, t.firstByte = time.Time{}, time.Time{}, time.Time{}
+	t.connReused = false
+}
+
+func (t *phaseTrace) record(ts *time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	*ts = time.Now()
+}
+
+func (t *phaseTrace) recordOnce(ts *time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if ts.IsZero() {
+		*ts = time.Now()
+	}
+}
+
+// phases returns the durations of the recorded phases. The transfer phase
+// lasts from the first response byte until the given end of the body read.
+// TTFB is the time between the request is written and the first response
+// byte, i.e. the server think time. Phases that did not happen are zero.
+func (t *phaseTrace) phases(end time.Time) phases {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	requestSent := t.wroteRequest
+	if requestSent.IsZero() {
+		requestSent = t.gotConn
+	}
+
+	return phases{
+		dns:        between(t.dnsStart, t.dnsDone),
+		connect:    between(t.connectStart, t.connectDone),
+		tls:        between(t.tlsStart, t.tlsDone),
+		ttfb:       between(requestSent, t.firstByte),
+		transfer:   between(t.firstByte, end),
+		connReused: t.connReused,
+	}
+}
+
+// between returns the duration between start and end, or zero if any of
+// them has not been recorded.
+func between(start, end time.Time) time.Duration {
+	if start.IsZero() || end.IsZero() || end.Before(start) {
+		return 0
+	}
+	return end.Sub(start)
+}
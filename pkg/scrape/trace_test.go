@@ -0,0 +1,90 @@
+package scrape
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHTTPScraper_ScrapePhases(t *testing.T) {
+	const thinkTime = 20 * time.Millisecond
+
+	server := httptest.NewTLSServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			time.Sleep(thinkTime)
+			_, _ = w.Write([]byte("7 bytes"))
+		},
+	))
+	defer server.Close()
+
+	s := newHTTPScraper(server.Client(), Target{URL: server.URL})
+
+	t.Run("should measure phases of a new connection", func(t *testing.T) {
+		res, err := s.Scrape(context.Background())
+
+		require.NoError(t, err)
+		assert.False(t, res.ConnReused)
+		assert.GreaterOrEqual(t, res.TTFBMs, int(thinkTime.Milliseconds()))
+		assert.LessOrEqual(t,
+			res.DNSTimeMs+res.ConnectTimeMs+res.TLSTimeMs+res.TTFBMs+res.TransferTimeMs,
+			res.ResponseTimeMs,
+		)
+	})
+
+	t.Run("should capture leaf certificate", func(t *testing.T) {
+		res, err := s.Scrape(context.Background())
+
+		require.NoError(t, err)
+		require.NotNil(t, res.Certificate)
+		leaf := server.Certificate()
+		assert.Equal(t, leaf.Subject.String(), res.Certificate.Subject)
+		assert.Equal(t, leaf.Issuer.String(), res.Certificate.Issuer)
+		assert.Contains(t, res.Certificate.SANs, "example.com")
+		assert.Contains(t, res.Certificate.SANs, "127.0.0.1")
+		assert.True(t, leaf.NotAfter.Equal(res.Certificate.NotAfter))
+	})
+
+	t.Run("should skip connection phases of a reused connection", func(t *testing.T) {
+		res, err := s.Scrape(context.Background())
+
+		require.NoError(t, err)
+		assert.True(t, res.ConnReused)
+		assert.Zero(t, res.DNSTimeMs)
+		assert.Zero(t, res.ConnectTimeMs)
+		assert.Zero(t, res.TLSTimeMs)
+		assert.GreaterOrEqual(t, res.TTFBMs, int(thinkTime.Milliseconds()))
+	})
+}
+
+func TestPhaseTrace_Phases(t *testing.T) {
+	start := time.Now()
+	at := func(ms int) time.Time {
+		return start.Add(time.Duration(ms) * time.Millisecond)
+	}
+	trace := phaseTrace{
+		dnsStart:     at(0),
+		dnsDone:      at(1),
+		connectStart: at(1),
+		connectDone:  at(3),
+		tlsStart:     at(3),
+		tlsDone:      at(6),
+		gotConn:      at(6),
+		wroteRequest: at(7),
+		firstByte:    at(11),
+	}
+
+	p := trace.phases(at(16))
+
+	assert.Equal(t, phases{
+		dns:      time.Millisecond,
+		connect:  2 * time.Millisecond,
+		tls:      3 * time.Millisecond,
+		ttfb:     4 * time.Millisecond,
+		transfer: 5 * time.Millisecond,
+	}, p)
+}
@@ -0,0 +1,451 @@
+package scrape
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"regexp"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// KindTransaction is the kind of TransactionScraper.
+const KindTransaction Kind = "transaction"
+
+// maxTransactionSteps limits the number of steps of a transaction.
+const maxTransactionSteps = 20
+
+var (
+	// varRe matches a variable name.
+	varRe = regexp.MustCompile(`^\w+$`)
+	// placeholderRe matches a {{variable}} placeholder.
+	placeholderRe = regexp.MustCompile(`\{\{\s*(\w+)\s*\}\}`)
+)
+
+// CaptureSource is a source a variable is captured from.
+type CaptureSource string
+
+// Possible capture sources.
+const (
+	CaptureJSON   CaptureSource = "json"
+	CaptureHeader CaptureSource = "header"
+	CaptureRegex  CaptureSource = "regex"
+)
+
+// Capture describes a variable captured from a step response: the value at
+// the JSON path of the body, the value of the header, or the first group of
+// the regex matched against the body, the whole match if it has no groups.
+type Capture struct {
+	Var    string        `json:"var"`
+	Source CaptureSource `json:"source"`
+	Path   string        `json:"path,omitempty"`
+	Header string        `json:"header,omitempty"`
+	Regex  string        `json:"regex,omitempty"`
+	// re is the compiled regex of a regex capture.
+	re *regexp.Regexp
+}
+
+// Validate checks that the capture is well-formed.
+func (c Capture) Validate() error {
+	if !varRe.MatchString(c.Var) {
+		return errors.Errorf("invalid variable name %q", c.Var)
+	}
+	switch c.Source {
+	case CaptureJSON:
+		if _, err := parseJSONPath(c.Path); err != nil {
+			return errors.Wrapf(err, "capture %s has invalid path", c.Var)
+		}
+	case CaptureHeader:
+		if c.Header == "" {
+			return errors.Errorf("capture %s requires a header", c.Var)
+		}
+	case CaptureRegex:
+		if _, err := regexp.Compile(c.Regex); err != nil {
+			return errors.Wrapf(err, "capture %s has invalid regex", c.Var)
+		}
+	default:
+		return errors.Errorf("capture %s has unsupported source %q", c.Var, c.Source)
+	}
+	return nil
+}
+
+// capture returns the captured value from the given response. The capture is
+// expected to be valid.
+func (c Capture) capture(resp *response) (string, error) {
+	switch c.Source {
+	case CaptureJSON:
+		return resp.lookup(c.Path)
+	case CaptureHeader:
+		if value := resp.header.Get(c.Header); value != "" {
+			return value, nil
+		}
+		return "", errors.Errorf("header %s not found", c.Header)
+	default:
+		re := c.re
+		if re == nil {
+			re = regexp.MustCompile(c.Regex)
+		}
+		match := re.FindSubmatch(resp.body)
+		switch {
+		case match == nil:
+			return "", errors.Errorf("regex %s does not match", c.Regex)
+		case len(match) > 1:
+			return string(match[1]), nil
+		default:
+			return string(match[0]), nil
+		}
+	}
+}
+
+// Step describes a single request of a transaction. URL, header values and
+// body may refer to the variables captured by the previous steps as
+// {{name}}. A relative URL is resolved against the target URL. A step fails
+// when it responds with a 4xx or 5xx status, before its assertions are
+// evaluated.
+type Step struct {
+	Name       string            `json:"name"`
+	URL        string            `json:"url"`
+	Method     string            `json:"method,omitempty"`
+	Headers    map[string]string `json:"headers,omitempty"`
+	Body       string            `json:"body,omitempty"`
+	Assertions []Assertion       `json:"assertions,omitempty"`
+	Captures   []Capture         `json:"captures,omitempty"`
+}
+
+// TransactionSettings defines the settings of a transaction target: the
+// ordered steps and the timeout of the whole transaction, 10s if empty.
+type TransactionSettings struct {
+	Steps   []Step `json:"steps"`
+	Timeout string `json:"timeout,omitempty"`
+}
+
+// StepResult represents a result of a single transaction step.
+type StepResult struct {
+	Name           string            `json:"name"`
+	StatusCode     int               `json:"status_code,omitempty"`
+	ResponseTimeMs int               `json:"response_time_ms"`
+	Assertions     []AssertionResult `json:"assertions,omitempty"`
+	Error          string            `json:"error,omitempty"`
+}
+
+// TransactionDetails contains the details of a transaction: the results of
+// the run steps and the name of the failed step, if any.
+type TransactionDetails struct {
+	Steps      []StepResult `json:"steps"`
+	FailedStep string       `json:"failed_step,omitempty"`
+}
+
+// TransactionScraper represents a scraper that runs ordered http steps
+// sharing a cookie jar. The steps stop at the first failed one.
+type TransactionScraper struct {
+	client  httpClient
+	base    *url.URL
+	steps   []Step
+	timeout time.Duration
+}
+
+// transactionFactory returns a factory of TransactionScrapers that use the
+// given client. Cookies are only kept if the client is an *http.Client.
+func transactionFactory(client httpClient) Factory {
+	return func(target Target) (Scraper, error) {
+		if err := target.validateNonHTTP(); err != nil {
+			return nil, err
+		}
+		if len(target.Assertions) > 0 {
+			return nil, errors.Errorf(
+				"assertions of %s probes are defined per step", KindTransaction,
+			)
+		}
+		base, err := url.Parse(target.URL)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid url %s", target.URL)
+		}
+
+		var settings TransactionSettings
+		if err = decodeSettings(target.Settings, &settings); err != nil {
+			return nil, err
+		}
+		timeout, err := parseTimeout(settings.Timeout)
+		if err != nil {
+			return nil, err
+		}
+		if err = validateSteps(settings.Steps); err != nil {
+			return nil, err
+		}
+		if !isHTTPURL(base) {
+			// relative step urls can not be resolved.
+			for _, step := range settings.Steps {
+				if ref, err := url.Parse(step.URL); err == nil && !ref.IsAbs() {
+					return nil, errors.Errorf(
+						"step %s has relative url %s, but url %s is not an absolute http url",
+						step.Name, step.URL, base.Redacted(),
+					)
+				}
+			}
+		}
+
+		return &TransactionScraper{
+			client:  client,
+			base:    base,
+			steps:   compileSteps(settings.Steps),
+			timeout: timeout,
+		}, nil
+	}
+}
+
+// compileSteps returns a copy of the valid steps with the regexes of their
+// assertions and captures compiled, so that they are not compiled on every
+// scrape.
+func compileSteps(steps []Step) []Step {
+	compiled := make([]Step, len(steps))
+	for i, step := range steps {
+		step.Assertions = compileAssertions(step.Assertions)
+		if len(step.Captures) > 0 {
+			captures := make([]Capture, len(step.Captures))
+			for j, c := range step.Captures {
+				if c.Source == CaptureRegex {
+					c.re = regexp.MustCompile(c.Regex)
+				}
+				captures[j] = c
+			}
+			step.Captures = captures
+		}
+		compiled[i] = step
+	}
+	return compiled
+}
+
+// validateSteps checks the steps and that they only refer to the variables
+// captured by the previous steps.
+func validateSteps(steps []Step) error {
+	if len(steps) == 0 {
+		return errors.New("transaction requires at least one step")
+	}
+	if len(steps) > maxTransactionSteps {
+		return errors.Errorf(
+			"transaction has more than %d steps", maxTransactionSteps,
+		)
+	}
+
+	names := make(map[string]bool, len(steps))
+	vars := make(map[string]bool)
+	for _, step := range steps {
+		if step.Name == "" {
+			return errors.New("step requires a name")
+		}
+		if names[step.Name] {
+			return errors.Errorf("step %s is defined twice", step.Name)
+		}
+		names[step.Name] = true
+
+		t := Target{
+			URL:        step.URL,
+			Method:     step.Method,
+			Headers:    step.Headers,
+			Body:       step.Body,
+			Assertions: step.Assertions,
+		}
+		if err := t.Validate(); err != nil {
+			return errors.Wrapf(err, "invalid step %s", step.Name)
+		}
+
+		templates := []string{step.URL, step.Body}
+		for _, value := range step.Headers {
+			templates = append(templates, value)
+		}
+		for _, tmpl := range templates {
+			for _, m := range placeholderRe.FindAllStringSubmatch(tmpl, -1) {
+				if !vars[m[1]] {
+					return errors.Errorf(
+						"step %s refers to undefined variable %s", step.Name, m[1],
+					)
+				}
+			}
+		}
+
+		for _, c := range step.Captures {
+			if err := c.Validate(); err != nil {
+				return errors.Wrapf(err, "invalid step %s", step.Name)
+			}
+			vars[c.Var] = true
+		}
+	}
+	return nil
+}
+
+// Scrape runs the steps in order with a new cookie jar. The status code of
+// the result is the one of the last run step, the response time is the time
+// of the whole transaction.
+func (s *TransactionScraper) Scrape(ctx context.Context) (Result, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		return Result{}, errors.Wrap(err, "failed to create cookie jar")
+	}
+	client := s.client
+	if c, ok := client.(*http.Client); ok {
+		withJar := *c
+		withJar.Jar = jar
+		client = &withJar
+	}
+
+	details := &TransactionDetails{}
+	m := Result{
+		Outcome: OutcomeSuccess,
+		Details: &Details{Transaction: details},
+	}
+	vars := make(map[string]string)
+
+	start := time.Now()
+	for _, step := range s.steps {
+		res, size, err := s.runStep(ctx, client, step, vars)
+		details.Steps = append(details.Steps, res)
+		m.StatusCode = res.StatusCode
+		m.ResponseSizeBytes += size
+		if err != nil {
+			details.FailedStep = step.Name
+			m.Outcome = OutcomeFailure
+			m.ErrorKind = kindOf(err)
+			m.ErrorMessage = fmt.Sprintf("step %s failed: %s", step.Name, err)
+			break
+		}
+	}
+	m.ResponseTimeMs = int(time.Since(start).Milliseconds())
+	m.CreatedAt = time.Now()
+
+	return m, nil
+}
+
+// runStep runs a single step, checks its status, evaluates its assertions and
+// captures its variables into vars. It returns the step result, the response
+// size and an error if the step failed.
+func (s *TransactionScraper) runStep(
+	ctx context.Context, client httpClient, step Step, vars map[string]string,
+) (StepResult, int64, error) {
+	res := StepResult{Name: step.Name}
+	fail := func(err error) (StepResult, int64, error) {
+		res.Error = err.Error()
+		return res, 0, err
+	}
+
+	// 1. Create the request with the captured variables
+	req, err := s.newRequest(ctx, step, vars)
+	if err != nil {
+		return fail(newError(ErrorKindInvalidURL, err))
+	}
+
+	// 2. Do the request and read the response
+	start := time.Now()
+	resp, err := client.Do(req)
+	if err != nil {
+		return fail(newError(
+			classifyRequestError(err),
+			errors.Wrapf(err, "request failed for %s", req.URL.Redacted()),
+		))
+	}
+	defer func() {
+		if err = resp.Body.Close(); err != nil {
+			log.Printf("failed to close response, %s", err)
+		}
+	}()
+
+	body := &limitedBuffer{limit: maxAssertedBodySize}
+	size, err := io.Copy(body, resp.Body)
+	if err != nil {
+		return fail(newError(
+			classifyReadError(err),
+			errors.Wrapf(err, "failed to read response for %s", req.URL.Redacted()),
+		))
+	}
+	res.StatusCode = resp.StatusCode
+	res.ResponseTimeMs = int(time.Since(start).Milliseconds())
+
+	// 3. Check the status, evaluate the assertions and capture the variables
+	if resp.StatusCode >= http.StatusBadRequest {
+		err = errors.Errorf("unexpected status %d", resp.StatusCode)
+		res.Error = err.Error()
+		return res, size, newError(ErrorKindStatus, err)
+	}
+	r := &response{
+		header:    resp.Header,
+		body:      body.Bytes(),
+		truncated: body.Truncated(),
+	}
+	if len(step.Assertions) > 0 {
+		res.Assertions, err = evaluateAssertions(step.Assertions, r)
+		if err != nil {
+			res.Error = err.Error()
+			return res, size, newError(ErrorKindAssertion, err)
+		}
+	}
+	for _, c := range step.Captures {
+		value, err := c.capture(r)
+		if err != nil {
+			err = errors.Wrapf(err, "failed to capture %s", c.Var)
+			res.Error = err.Error()
+			return res, size, newError(ErrorKindAssertion, err)
+		}
+		vars[c.Var] = value
+	}
+
+	return res, size, nil
+}
+
+// newRequest creates the request of the given step with the variables
+// substituted.
+func (s *TransactionScraper) newRequest(
+	ctx context.Context, step Step, vars map[string]string,
+) (*http.Request, error) {
+	rawURL, err := expand(step.URL, vars)
+	if err != nil {
+		return nil, err
+	}
+	ref, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid url %s", rawURL)
+	}
+	u := s.base.ResolveReference(ref)
+	if !isHTTPURL(u) {
+		return nil, errors.Errorf("unsupported url %s", u.Redacted())
+	}
+
+	t := Target{URL: u.String(), Method: step.Method}
+	if t.Body, err = expand(step.Body, vars); err != nil {
+		return nil, err
+	}
+	if len(step.Headers) > 0 {
+		t.Headers = make(map[string]string, len(step.Headers))
+		for name, value := range step.Headers {
+			if t.Headers[name], err = expand(value, vars); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return t.newRequest(ctx)
+}
+
+// expand substitutes the {{name}} placeholders with the variables.
+func expand(s string, vars map[string]string) (string, error) {
+	var err error
+	res := placeholderRe.ReplaceAllStringFunc(s, func(p string) string {
+		name := placeholderRe.FindStringSubmatch(p)[1]
+		value, ok := vars[name]
+		if !ok && err == nil {
+			err = errors.Errorf("undefined variable %s", name)
+		}
+		return value
+	})
+	return res, err
+}
+
+// isHTTPURL reports whether the url is an absolute http or https url.
+func isHTTPURL(u *url.URL) bool {
+	return (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
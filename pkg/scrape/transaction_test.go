@@ -0,0 +1,315 @@
+package scrape
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCapture_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		capture Capture
+		wantErr string
+	}{
+		{
+			name:    "should return error on invalid variable name",
+			capture: Capture{Var: "a-b", Source: CaptureHeader, Header: "X"},
+			wantErr: "invalid variable name",
+		},
+		{
+			name:    "should return error on unsupported source",
+			capture: Capture{Var: "a", Source: "cookie"},
+			wantErr: "unsupported source",
+		},
+		{
+			name:    "should return error on invalid path",
+			capture: Capture{Var: "a", Source: CaptureJSON, Path: "token"},
+			wantErr: "invalid path",
+		},
+		{
+			name:    "should return error on missing header",
+			capture: Capture{Var: "a", Source: CaptureHeader},
+			wantErr: "requires a header",
+		},
+		{
+			name:    "should return error on invalid regex",
+			capture: Capture{Var: "a", Source: CaptureRegex, Regex: "("},
+			wantErr: "invalid regex",
+		},
+		{
+			name:    "should accept valid capture",
+			capture: Capture{Var: "token", Source: CaptureJSON, Path: "$.token"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.capture.Validate()
+
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Regexp(t, tt.wantErr, err)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestTransactionFactory(t *testing.T) {
+	factory := transactionFactory(newOKClient())
+
+	tests := []struct {
+		name    string
+		url     string
+		steps   []Step
+		wantErr string
+	}{
+		{
+			name:    "should return error on no steps",
+			wantErr: "at least one step",
+		},
+		{
+			name:    "should return error on missing name",
+			steps:   []Step{{URL: "/"}},
+			wantErr: "requires a name",
+		},
+		{
+			name:    "should return error on duplicate name",
+			steps:   []Step{{Name: "a", URL: "/"}, {Name: "a", URL: "/"}},
+			wantErr: "defined twice",
+		},
+		{
+			name:    "should return error on invalid step request",
+			steps:   []Step{{Name: "a", URL: "/", Method: "BREW"}},
+			wantErr: "invalid step a: unsupported method",
+		},
+		{
+			name: "should return error on undefined variable",
+			steps: []Step{
+				{Name: "a", URL: "/", Headers: map[string]string{"X": "{{token}}"}},
+			},
+			wantErr: "undefined variable token",
+		},
+		{
+			name: "should return error on variable captured by later step",
+			steps: []Step{
+				{Name: "a", URL: "/{{id}}"},
+				{Name: "b", URL: "/", Captures: []Capture{
+					{Var: "id", Source: CaptureRegex, Regex: `\d+`},
+				}},
+			},
+			wantErr: "undefined variable id",
+		},
+		{
+			name:    "should return error on relative step url without base url",
+			url:     "example.com",
+			steps:   []Step{{Name: "a", URL: "/"}},
+			wantErr: "step a has relative url /",
+		},
+		{
+			name:  "should create scraper with absolute step urls without base url",
+			url:   "example.com",
+			steps: []Step{{Name: "a", URL: "https://example.com/"}},
+		},
+		{
+			name: "should create scraper",
+			steps: []Step{
+				{Name: "a", URL: "/", Captures: []Capture{
+					{Var: "id", Source: CaptureRegex, Regex: `\d+`},
+				}},
+				{Name: "b", URL: "/items/{{ id }}"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			settings, err := json.Marshal(TransactionSettings{Steps: tt.steps})
+			require.NoError(t, err)
+			url := "https://example.com"
+			if tt.url != "" {
+				url = tt.url
+			}
+
+			s, err := factory(Target{
+				Kind:     KindTransaction,
+				URL:      url,
+				Settings: settings,
+			})
+
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Regexp(t, tt.wantErr, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.IsType(t, &TransactionScraper{}, s)
+		})
+	}
+}
+
+func TestTransactionScraper_Scrape(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/login", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.FormValue("user") != "admin" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		http.SetCookie(w, &http.Cookie{Name: "session", Value: "s1", Path: "/"})
+		http.Redirect(w, r, "/welcome", http.StatusFound)
+	})
+	mux.HandleFunc("/welcome", func(w http.ResponseWriter, r *http.Request) {
+		if c, err := r.Cookie("session"); err != nil || c.Value != "s1" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("X-Request-Id", "42")
+		_, _ = fmt.Fprint(w, `{"token":"t1","user":{"id":7}}`)
+	})
+	mux.HandleFunc("/api/users/7", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer t1" ||
+			r.Header.Get("X-Request-Id") != "42" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		_, _ = fmt.Fprint(w, `<p>status: active</p>`)
+	})
+	mux.HandleFunc("/broken", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	login := Step{
+		Name:    "login",
+		URL:     "/login",
+		Method:  http.MethodPost,
+		Headers: map[string]string{"Content-Type": "application/x-www-form-urlencoded"},
+		Body:    "user=admin",
+		Assertions: []Assertion{
+			{Type: AssertionJSONPathExists, Path: "$.token"},
+		},
+		Captures: []Capture{
+			{Var: "token", Source: CaptureJSON, Path: "$.token"},
+			{Var: "id", Source: CaptureJSON, Path: "$.user.id"},
+			{Var: "request_id", Source: CaptureHeader, Header: "X-Request-Id"},
+		},
+	}
+	api := Step{
+		Name: "api",
+		URL:  server.URL + "/api/users/{{id}}",
+		Headers: map[string]string{
+			"Authorization": "Bearer {{token}}",
+			"X-Request-Id":  "{{request_id}}",
+		},
+		Captures: []Capture{
+			{Var: "status", Source: CaptureRegex, Regex: `status: (\w+)`},
+		},
+	}
+	newScraper := func(t *testing.T, steps ...Step) Scraper {
+		settings, err := json.Marshal(TransactionSettings{Steps: steps})
+		require.NoError(t, err)
+		s, err := transactionFactory(NewHTTPClient(time.Second))(Target{
+			Kind:     KindTransaction,
+			URL:      server.URL,
+			Settings: settings,
+		})
+		require.NoError(t, err)
+		return s
+	}
+
+	t.Run("should run steps with shared cookies and variables", func(t *testing.T) {
+		res, err := newScraper(t, login, api).Scrape(context.Background())
+
+		require.NoError(t, err)
+		assert.Equal(t, OutcomeSuccess, res.Outcome, res.ErrorMessage)
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+		details := res.Details.Transaction
+		require.NotNil(t, details)
+		assert.Empty(t, details.FailedStep)
+		require.Len(t, details.Steps, 2)
+		assert.Equal(t, "login", details.Steps[0].Name)
+		assert.Equal(t, http.StatusOK, details.Steps[0].StatusCode)
+		assert.Len(t, details.Steps[0].Assertions, 1)
+		assert.Equal(t, "api", details.Steps[1].Name)
+	})
+
+	t.Run("should not share cookies between scrapes", func(t *testing.T) {
+		welcome := Step{Name: "welcome", URL: "/welcome", Assertions: []Assertion{
+			{Type: AssertionContains, Value: "token"},
+		}}
+		s := newScraper(t, welcome)
+
+		res, err := s.Scrape(context.Background())
+
+		require.NoError(t, err)
+		assert.Equal(t, OutcomeFailure, res.Outcome)
+		assert.Equal(t, http.StatusUnauthorized, res.StatusCode)
+	})
+
+	t.Run("should stop at first failed step", func(t *testing.T) {
+		badLogin := login
+		badLogin.Body = "user=guest"
+
+		res, err := newScraper(t, badLogin, api).Scrape(context.Background())
+
+		require.NoError(t, err)
+		assert.Equal(t, OutcomeFailure, res.Outcome)
+		assert.Equal(t, ErrorKindStatus, res.ErrorKind)
+		assert.Regexp(t, "step login failed: unexpected status 401", res.ErrorMessage)
+		details := res.Details.Transaction
+		assert.Equal(t, "login", details.FailedStep)
+		require.Len(t, details.Steps, 1)
+		assert.Equal(t, http.StatusUnauthorized, details.Steps[0].StatusCode)
+		assert.NotEmpty(t, details.Steps[0].Error)
+	})
+
+	t.Run("should fail step with error status without assertions", func(t *testing.T) {
+		broken := Step{Name: "broken", URL: "/broken"}
+		static := Step{Name: "static", URL: "/welcome"}
+
+		res, err := newScraper(t, broken, static).Scrape(context.Background())
+
+		require.NoError(t, err)
+		assert.Equal(t, OutcomeFailure, res.Outcome)
+		assert.Equal(t, ErrorKindStatus, res.ErrorKind)
+		assert.Equal(t, http.StatusInternalServerError, res.StatusCode)
+		details := res.Details.Transaction
+		assert.Equal(t, "broken", details.FailedStep)
+		require.Len(t, details.Steps, 1)
+	})
+
+	t.Run("should fail when variable cannot be captured", func(t *testing.T) {
+		missing := api
+		missing.Captures = []Capture{
+			{Var: "missing", Source: CaptureHeader, Header: "X-Missing"},
+		}
+
+		res, err := newScraper(t, login, missing).Scrape(context.Background())
+
+		require.NoError(t, err)
+		assert.Equal(t, OutcomeFailure, res.Outcome)
+		assert.Equal(t, "api", res.Details.Transaction.FailedStep)
+		assert.Regexp(t, "failed to capture missing", res.ErrorMessage)
+	})
+
+	t.Run("should fail on request error", func(t *testing.T) {
+		unreachable := Step{Name: "unreachable", URL: "http://127.0.0.1:1/"}
+
+		res, err := newScraper(t, unreachable).Scrape(context.Background())
+
+		require.NoError(t, err)
+		assert.Equal(t, OutcomeFailure, res.Outcome)
+		assert.Equal(t, ErrorKindConnect, res.ErrorKind)
+		assert.Equal(t, "unreachable", res.Details.Transaction.FailedStep)
+	})
+}
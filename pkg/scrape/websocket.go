@@ -0,0 +1,254 @@
+package scrape
+
+import (
+	"context"
+	"crypto/tls"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/pkg/errors"
+)
+
+// KindWebSocket is the kind of WebSocketScraper.
+const KindWebSocket Kind = "websocket"
+
+// closeTimeout limits the wait for the close reply of the server.
+const closeTimeout = time.Second
+
+// WebSocketSettings defines the settings of a websocket target. Send is sent
+// as a text message after the handshake. Expect and ExpectRegex are checked
+// against every received message until one of them matches, the scrape fails
+// if none does. Timeout limits the whole scrape, 10s if empty.
+type WebSocketSettings struct {
+	Send        string `json:"send,omitempty"`
+	Expect      string `json:"expect,omitempty"`
+	ExpectRegex string `json:"expect_regex,omitempty"`
+	Timeout     string `json:"timeout,omitempty"`
+}
+
+// WebSocketDetails contains the details of a websocket scrape: the handshake
+// and the round-trip latencies, the beginning of the reply and the close code
+// and text sent by the server.
+type WebSocketDetails struct {
+	HandshakeMs int    `json:"handshake_ms"`
+	RoundTripMs int    `json:"round_trip_ms"`
+	Reply       string `json:"reply,omitempty"`
+	CloseCode   int    `json:"close_code,omitempty"`
+	CloseText   string `json:"close_text,omitempty"`
+}
+
+// WebSocketScraper represents a scraper that performs the websocket handshake
+// with the ws or wss URL of the target and, if configured, exchanges a
+// message. Headers and auth of the target are sent with the handshake.
+type WebSocketScraper struct {
+	target     Target
+	send       string
+	timeout    time.Duration
+	assertions []Assertion
+}
+
+// webSocketFactory creates WebSocketScrapers.
+func webSocketFactory(target Target) (Scraper, error) {
+	if target.method() != http.MethodGet || target.Body != "" ||
+		target.RedirectPolicy != nil || target.Script != "" {
+		return nil, errors.Errorf(
+			"method, body, redirect policy and script are not supported by %s probes",
+			KindWebSocket,
+		)
+	}
+	if !strings.HasPrefix(target.URL, "ws://") &&
+		!strings.HasPrefix(target.URL, "wss://") {
+		return nil, errors.Errorf("unsupported url %s", target.URL)
+	}
+	if err := validateHeaders(target.Headers); err != nil {
+		return nil, err
+	}
+	if target.Auth != nil {
+		if err := target.Auth.Validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	var settings WebSocketSettings
+	if err := decodeSettings(target.Settings, &settings); err != nil {
+		return nil, err
+	}
+	timeout, err := parseTimeout(settings.Timeout)
+	if err != nil {
+		return nil, err
+	}
+
+	assertions := append(
+		expectAssertions(settings.Expect, settings.ExpectRegex),
+		target.Assertions...,
+	)
+	if err = validateBodyAssertions(assertions); err != nil {
+		return nil, err
+	}
+
+	return &WebSocketScraper{
+		target:     target,
+		send:       settings.Send,
+		timeout:    timeout,
+		assertions: compileAssertions(assertions),
+	}, nil
+}
+
+// Scrape performs the handshake, exchanges the configured message and closes
+// the connection. A handshake rejected by the server fails the scrape with the
+// status error kind.
+func (s *WebSocketScraper) Scrape(ctx context.Context) (Result, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	req, err := s.target.newRequest(ctx)
+	if err != nil {
+		return Result{}, newError(
+			ErrorKindInvalidURL,
+			errors.Wrapf(err, "failed to create request for %s", s.target.URL),
+		)
+	}
+	header := req.Header.Clone()
+	if req.Host != "" {
+		header.Set("Host", req.Host)
+	}
+
+	// 1. Perform the handshake
+	start := time.Now()
+	conn, resp, err := websocket.DefaultDialer.DialContext(ctx, s.target.URL, header)
+	if err != nil {
+		if errors.Is(err, websocket.ErrBadHandshake) && resp != nil {
+			return Result{}, newError(
+				ErrorKindStatus,
+				errors.Errorf(
+					"handshake with %s failed with status %d",
+					s.target.URL, resp.StatusCode,
+				),
+			)
+		}
+		return Result{}, newError(
+			classifyRequestError(err),
+			errors.Wrapf(err, "handshake failed for %s", s.target.URL),
+		)
+	}
+	defer conn.Close()
+	handshake := time.Since(start)
+	// a larger message fails the read, it is not buffered.
+	conn.SetReadLimit(maxAssertedBodySize)
+
+	details := &WebSocketDetails{HandshakeMs: int(handshake.Milliseconds())}
+	m := Result{
+		Outcome:    OutcomeSuccess,
+		StatusCode: resp.StatusCode,
+		Details:    &Details{WebSocket: details},
+	}
+	if tlsConn, ok := conn.UnderlyingConn().(*tls.Conn); ok {
+		state := tlsConn.ConnectionState()
+		m.Certificate = leafCertificate(&state)
+	}
+
+	deadline, _ := ctx.Deadline()
+	_ = conn.SetReadDeadline(deadline)
+	_ = conn.SetWriteDeadline(deadline)
+	defer interruptOnCancel(ctx, conn.UnderlyingConn())()
+
+	// 2. Send the message and wait for the reply
+	if s.send != "" || len(s.assertions) > 0 {
+		sent := time.Now()
+		if s.send != "" {
+			if err = conn.WriteMessage(websocket.TextMessage, []byte(s.send)); err != nil {
+				return Result{}, newError(
+					classifyReadError(err),
+					errors.Wrapf(err, "failed to send message to %s", s.target.URL),
+				)
+			}
+		}
+
+		reply, err := s.receive(conn)
+		if err != nil && (reply == nil || errors.Is(err, websocket.ErrReadLimit)) {
+			return Result{}, newError(
+				classifyReadError(err),
+				errors.Wrapf(err, "failed to receive reply from %s", s.target.URL),
+			)
+		}
+		details.RoundTripMs = int(time.Since(sent).Milliseconds())
+		m.ResponseSizeBytes = int64(len(reply))
+		if len(reply) > maxDetailsResponseSize {
+			details.Reply = printable(reply[:maxDetailsResponseSize])
+		} else {
+			details.Reply = printable(reply)
+		}
+
+		if len(s.assertions) > 0 {
+			m.Assertions, err = evaluateAssertions(s.assertions, &response{body: reply})
+			if err != nil {
+				m.Outcome = OutcomeFailure
+				m.ErrorKind = ErrorKindAssertion
+				m.ErrorMessage = err.Error()
+			}
+		}
+	}
+
+	// 3. Close the connection and wait for the close reply of the server
+	m.ResponseTimeMs = int(time.Since(start).Milliseconds())
+	s.close(conn, details)
+	m.CreatedAt = time.Now()
+
+	return m, nil
+}
+
+// receive reads messages until one of them passes the assertions and returns
+// it. Without assertions the first message is returned. If no message passes,
+// the last received message is returned along with the read error. Messages
+// longer than the read limit fail the read with websocket.ErrReadLimit.
+func (s *WebSocketScraper) receive(conn *websocket.Conn) ([]byte, error) {
+	var last []byte
+	for {
+		_, msg, err := conn.ReadMessage()
+		if err != nil {
+			return last, err
+		}
+		last = msg
+		if len(s.assertions) == 0 {
+			return msg, nil
+		}
+		if _, err = evaluateAssertions(s.assertions, &response{body: msg}); err == nil {
+			return msg, nil
+		}
+	}
+}
+
+// close sends the normal closure to the server and records the close code of
+// its reply.
+func (s *WebSocketScraper) close(conn *websocket.Conn, details *WebSocketDetails) {
+	deadline := time.Now().Add(closeTimeout)
+	err := conn.WriteControl(
+		websocket.CloseMessage,
+		websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""),
+		deadline,
+	)
+	if err != nil {
+		s.recordClose(details, err)
+		return
+	}
+
+	_ = conn.SetReadDeadline(deadline)
+	for {
+		if _, _, err = conn.ReadMessage(); err != nil {
+			s.recordClose(details, err)
+			return
+		}
+	}
+}
+
+// recordClose records the close code and text if the given error is a close
+// error.
+func (s *WebSocketScraper) recordClose(details *WebSocketDetails, err error) {
+	var closeErr *websocket.CloseError
+	if errors.As(err, &closeErr) {
+		details.CloseCode = closeErr.Code
+		details.CloseText = closeErr.Text
+	}
+}
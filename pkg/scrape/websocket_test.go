@@ -0,0 +1,228 @@
+package scrape
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWebSocketFactory(t *testing.T) {
+	tests := []struct {
+		name    string
+		target  Target
+		wantErr string
+	}{
+		{
+			name:    "should return error on http url",
+			target:  Target{URL: "https://example.com/ws"},
+			wantErr: "unsupported url",
+		},
+		{
+			name:    "should return error on method",
+			target:  Target{URL: "wss://example.com/ws", Method: "POST"},
+			wantErr: "not supported",
+		},
+		{
+			name: "should return error on script",
+			target: Target{
+				URL:    "wss://example.com/ws",
+				Script: "check(true)",
+			},
+			wantErr: "script are not supported",
+		},
+		{
+			name: "should return error on invalid header",
+			target: Target{
+				URL:     "wss://example.com/ws",
+				Headers: map[string]string{"X-Bad": "a\r\nb"},
+			},
+			wantErr: "invalid value of header",
+		},
+		{
+			name: "should return error on invalid auth",
+			target: Target{
+				URL:  "wss://example.com/ws",
+				Auth: &Auth{Type: AuthBearer},
+			},
+			wantErr: "token",
+		},
+		{
+			name: "should create scraper",
+			target: Target{
+				URL:      "wss://example.com/ws",
+				Headers:  map[string]string{"Origin": "https://example.com"},
+				Settings: json.RawMessage(`{"send":"ping","expect":"pong"}`),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			target := tt.target
+			target.Kind = KindWebSocket
+
+			s, err := webSocketFactory(target)
+
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Regexp(t, tt.wantErr, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.IsType(t, &WebSocketScraper{}, s)
+		})
+	}
+}
+
+func TestWebSocketScraper_Scrape(t *testing.T) {
+	upgrader := websocket.Upgrader{}
+	server := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Authorization") != "Bearer token" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			conn, err := upgrader.Upgrade(w, r, nil)
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+			_ = conn.WriteMessage(websocket.TextMessage, []byte("welcome"))
+			for {
+				_, msg, err := conn.ReadMessage()
+				if err != nil {
+					return
+				}
+				switch string(msg) {
+				case "ping":
+					_ = conn.WriteMessage(websocket.TextMessage, []byte("pong"))
+				case "binary":
+					_ = conn.WriteMessage(websocket.BinaryMessage, []byte("\x00\x01pong"))
+				case "large":
+					large := strings.Repeat("x", maxAssertedBodySize+1)
+					_ = conn.WriteMessage(websocket.TextMessage, []byte(large))
+				case "bye":
+					_ = conn.WriteMessage(websocket.CloseMessage,
+						websocket.FormatCloseMessage(websocket.CloseTryAgainLater, "busy"))
+					return
+				}
+			}
+		},
+	))
+	defer server.Close()
+	wsURL := "ws" + strings.TrimPrefix(server.URL, "http")
+	auth := &Auth{Type: AuthBearer, Token: "token"}
+
+	t.Run("should return error on rejected handshake", func(t *testing.T) {
+		_, err := newTestWebSocketScraper(t, wsURL, nil, `{}`).Scrape(context.Background())
+
+		require.Error(t, err)
+		assert.Equal(t, ErrorKindStatus, kindOf(err))
+		assert.Regexp(t, "401", err)
+	})
+
+	t.Run("should perform handshake", func(t *testing.T) {
+		res, err := newTestWebSocketScraper(t, wsURL, auth, `{}`).Scrape(context.Background())
+
+		require.NoError(t, err)
+		assert.Equal(t, OutcomeSuccess, res.Outcome)
+		assert.Equal(t, http.StatusSwitchingProtocols, res.StatusCode)
+		require.NotNil(t, res.Details.WebSocket)
+		assert.Equal(t, websocket.CloseNormalClosure, res.Details.WebSocket.CloseCode)
+	})
+
+	t.Run("should wait for matching reply", func(t *testing.T) {
+		res, err := newTestWebSocketScraper(
+			t, wsURL, auth, `{"send":"ping","expect":"pong"}`,
+		).Scrape(context.Background())
+
+		require.NoError(t, err)
+		assert.Equal(t, OutcomeSuccess, res.Outcome, res.ErrorMessage)
+		assert.Equal(t, "pong", res.Details.WebSocket.Reply)
+		require.Len(t, res.Assertions, 1)
+		assert.True(t, res.Assertions[0].Passed)
+	})
+
+	t.Run("should escape binary reply", func(t *testing.T) {
+		res, err := newTestWebSocketScraper(
+			t, wsURL, auth, `{"send":"binary","expect":"pong"}`,
+		).Scrape(context.Background())
+
+		require.NoError(t, err)
+		assert.Equal(t, OutcomeSuccess, res.Outcome, res.ErrorMessage)
+		assert.Equal(t, `\x00\x01pong`, res.Details.WebSocket.Reply)
+	})
+
+	t.Run("should fail when no reply matches", func(t *testing.T) {
+		res, err := newTestWebSocketScraper(
+			t, wsURL, auth, `{"send":"ping","expect_regex":"^PONG$","timeout":"200ms"}`,
+		).Scrape(context.Background())
+
+		require.NoError(t, err)
+		assert.Equal(t, OutcomeFailure, res.Outcome)
+		assert.Equal(t, ErrorKindAssertion, res.ErrorKind)
+		assert.Equal(t, "pong", res.Details.WebSocket.Reply)
+	})
+
+	t.Run("should return read error on too large reply", func(t *testing.T) {
+		_, err := newTestWebSocketScraper(
+			t, wsURL, auth, `{"send":"large","expect":"pong"}`,
+		).Scrape(context.Background())
+
+		require.Error(t, err)
+		assert.Equal(t, ErrorKindRead, kindOf(err))
+		assert.Regexp(t, "read limit exceeded", err)
+	})
+
+	t.Run("should record close code of server", func(t *testing.T) {
+		res, err := newTestWebSocketScraper(t, wsURL, auth, `{"send":"bye"}`).Scrape(context.Background())
+
+		require.NoError(t, err)
+		assert.Equal(t, "welcome", res.Details.WebSocket.Reply)
+		assert.Equal(t, websocket.CloseTryAgainLater, res.Details.WebSocket.CloseCode)
+		assert.Equal(t, "busy", res.Details.WebSocket.CloseText)
+	})
+
+	t.Run("should return timeout error when nothing is received", func(t *testing.T) {
+		silent := httptest.NewServer(http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {
+				conn, err := upgrader.Upgrade(w, r, nil)
+				if err != nil {
+					return
+				}
+				defer conn.Close()
+				time.Sleep(time.Second)
+			},
+		))
+		defer silent.Close()
+
+		_, err := newTestWebSocketScraper(
+			t, "ws"+strings.TrimPrefix(silent.URL, "http"), nil,
+			`{"expect":"pong","timeout":"100ms"}`,
+		).Scrape(context.Background())
+
+		require.Error(t, err)
+		assert.Equal(t, ErrorKindTimeout, kindOf(err))
+	})
+}
+
+func newTestWebSocketScraper(
+	t *testing.T, url string, auth *Auth, settings string,
+) Scraper {
+	s, err := webSocketFactory(Target{
+		Kind:     KindWebSocket,
+		URL:      url,
+		Auth:     auth,
+		Settings: json.RawMessage(settings),
+	})
+	require.NoError(t, err)
+	return s
+}
@@ -0,0 +1,4 @@
+ALTER TABLE configs
+    DROP COLUMN IF EXISTS kind,
+    DROP COLUMN IF EXISTS settings,
+    ADD CONSTRAINT configs_url_key UNIQUE (url);
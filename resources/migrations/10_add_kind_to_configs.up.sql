@@ -0,0 +1,4 @@
+ALTER TABLE configs
+    ADD COLUMN kind TEXT NOT NULL DEFAULT '',
+    ADD COLUMN settings JSONB DEFAULT NULL,
+    DROP CONSTRAINT IF EXISTS configs_url_key;
@@ -0,0 +1,5 @@
+ALTER TABLE configs
+    DROP COLUMN IF EXISTS script;
+
+ALTER TABLE metrics
+    DROP COLUMN IF EXISTS script;
@@ -0,0 +1,5 @@
+ALTER TABLE configs
+    ADD COLUMN script TEXT NOT NULL DEFAULT '';
+
+ALTER TABLE metrics
+    ADD COLUMN script JSONB DEFAULT NULL;
@@ -0,0 +1,5 @@
+ALTER TABLE configs
+    DROP COLUMN IF EXISTS retry;
+
+ALTER TABLE metrics
+    DROP COLUMN IF EXISTS attempts;
@@ -0,0 +1,5 @@
+ALTER TABLE configs
+    ADD COLUMN retry JSONB DEFAULT NULL;
+
+ALTER TABLE metrics
+    ADD COLUMN attempts JSONB DEFAULT NULL;
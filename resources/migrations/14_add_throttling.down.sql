@@ -0,0 +1,3 @@
+ALTER TABLE metrics
+    DROP COLUMN IF EXISTS throttled,
+    DROP COLUMN IF EXISTS retry_after;
@@ -0,0 +1,3 @@
+ALTER TABLE metrics
+    ADD COLUMN throttled   BOOLEAN NOT NULL DEFAULT FALSE,
+    ADD COLUMN retry_after INTEGER NOT NULL DEFAULT 0;
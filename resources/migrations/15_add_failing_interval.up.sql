@@ -0,0 +1,2 @@
+ALTER TABLE configs
+    ADD COLUMN failing JSONB DEFAULT NULL;
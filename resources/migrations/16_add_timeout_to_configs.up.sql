@@ -0,0 +1,2 @@
+ALTER TABLE configs
+    ADD COLUMN timeout TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,12 @@
+CREATE TABLE instances
+(
+    id           TEXT PRIMARY KEY,
+    heartbeat_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE leases
+(
+    config_name TEXT PRIMARY KEY,
+    instance_id TEXT                     NOT NULL,
+    expires_at  TIMESTAMP WITH TIME ZONE NOT NULL
+);
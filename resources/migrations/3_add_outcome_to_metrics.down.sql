@@ -0,0 +1,4 @@
+ALTER TABLE metrics
+    DROP COLUMN IF EXISTS outcome,
+    DROP COLUMN IF EXISTS error_kind,
+    DROP COLUMN IF EXISTS error_message;
@@ -0,0 +1,4 @@
+ALTER TABLE metrics
+    ADD COLUMN outcome       TEXT NOT NULL DEFAULT 'success',
+    ADD COLUMN error_kind    TEXT NOT NULL DEFAULT '',
+    ADD COLUMN error_message TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,7 @@
+ALTER TABLE metrics
+    DROP COLUMN IF EXISTS dns_time,
+    DROP COLUMN IF EXISTS connect_time,
+    DROP COLUMN IF EXISTS tls_time,
+    DROP COLUMN IF EXISTS ttfb,
+    DROP COLUMN IF EXISTS transfer_time,
+    DROP COLUMN IF EXISTS conn_reused;
@@ -0,0 +1,7 @@
+ALTER TABLE metrics
+    ADD COLUMN dns_time      INTEGER NOT NULL DEFAULT 0,
+    ADD COLUMN connect_time  INTEGER NOT NULL DEFAULT 0,
+    ADD COLUMN tls_time      INTEGER NOT NULL DEFAULT 0,
+    ADD COLUMN ttfb          INTEGER NOT NULL DEFAULT 0,
+    ADD COLUMN transfer_time INTEGER NOT NULL DEFAULT 0,
+    ADD COLUMN conn_reused   BOOLEAN NOT NULL DEFAULT FALSE;
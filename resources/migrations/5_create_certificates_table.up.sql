@@ -0,0 +1,11 @@
+CREATE TABLE certificates
+(
+    name       TEXT PRIMARY KEY,
+    subject    TEXT                     NOT NULL,
+    issuer     TEXT                     NOT NULL,
+    sans       TEXT[]                   NOT NULL DEFAULT '{}',
+    not_after  TIMESTAMP WITH TIME ZONE NOT NULL,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+
+    FOREIGN KEY (name) REFERENCES configs (name)
+);
@@ -0,0 +1,5 @@
+ALTER TABLE configs
+    ADD COLUMN assertions JSONB DEFAULT NULL;
+
+ALTER TABLE metrics
+    ADD COLUMN assertions JSONB DEFAULT NULL;
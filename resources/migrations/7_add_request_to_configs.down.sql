@@ -0,0 +1,4 @@
+ALTER TABLE configs
+    DROP COLUMN IF EXISTS method,
+    DROP COLUMN IF EXISTS headers,
+    DROP COLUMN IF EXISTS body;
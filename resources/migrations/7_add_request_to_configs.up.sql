@@ -0,0 +1,4 @@
+ALTER TABLE configs
+    ADD COLUMN method  TEXT NOT NULL DEFAULT '',
+    ADD COLUMN headers JSONB         DEFAULT NULL,
+    ADD COLUMN body    TEXT NOT NULL DEFAULT '';
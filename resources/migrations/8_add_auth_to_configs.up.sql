@@ -0,0 +1,2 @@
+ALTER TABLE configs
+    ADD COLUMN auth JSONB DEFAULT NULL;
@@ -0,0 +1,5 @@
+ALTER TABLE configs
+    ADD COLUMN redirect_policy JSONB DEFAULT NULL;
+
+ALTER TABLE metrics
+    ADD COLUMN redirects JSONB DEFAULT NULL;